@@ -14,7 +14,26 @@ func main() {
 	var paths []string
 	var exclude []string
 	var outputFormat string
-	var blocklistPath string
+	var jsonInventory bool
+	var blocklistPaths []string
+	var osvPath string
+	var fileHashesPath string
+	var networkIoCsPath string
+	var network bool
+	var typosquat bool
+	var iocInclude []string
+	var iocExclude []string
+	var maxFileSize int64
+	var allMatches bool
+	var rulesPaths []string
+	var defaultRules bool
+	var maxMatchesPerRule int
+	var maxMatchesPerFile int
+	var maxDecodeDepth int
+	var obfuscation bool
+	var unicodeCheck bool
+	var binaries bool
+	var obfuscationMinScore float64
 
 	rootCmd := &cobra.Command{
 		Use:   "npm-malicious",
@@ -37,33 +56,81 @@ func main() {
 			// Create dependency reader
 			reader := scanner.NewDependencyReader()
 
-			// Load blocklist if provided
-			var blocklist *scanner.Blocklist
-			if blocklistPath != "" {
-				blocklist, err = scanner.LoadBlocklist(blocklistPath)
+			// Load and merge blocklists and OSV advisories if provided
+			blocklist := loadBlocklists(blocklistPaths, osvPath)
+
+			// Load IoC rules: the built-in pack, tuned or extended by rule files
+			rules := []scanner.Rule{}
+			if defaultRules {
+				rules = scanner.DefaultRules()
+			}
+			for _, rulesPath := range rulesPaths {
+				loaded, err := scanner.LoadRules(rulesPath)
 				if err != nil {
-					log.Printf("Warning: Failed to load blocklist from %s: %v", blocklistPath, err)
-				} else {
-					fmt.Printf("Loaded blocklist with %d entries\n", len(blocklist.Entries))
+					log.Printf("Warning: Failed to load rules from %s: %v", rulesPath, err)
+					continue
 				}
+				fmt.Printf("Loaded %d rules from %s\n", len(loaded), rulesPath)
+				rules = scanner.MergeRules(rules, loaded)
 			}
 
-			// Create IoC scanner with common malicious patterns
-			iocPatterns := []string{
-				`eval\(.*\)`,                 // eval() usage
-				`child_process`,              // child process spawning
-				`fs\.unlinkSync`,             // file deletion
-				`process\.env\[.*\]`,         // environment variable access
-				`require\(['"]http['"]\)`,    // http requests
-				`bitcoin|crypto|wallet`,      // crypto-related
-				`password|passwd|credential`, // credential harvesting
-				`download|fetch.*\.exe`,      // executable downloads
-			}
-			iocScanner, err := scanner.NewIoCScanner(iocPatterns, 5)
+			iocScanner, err := scanner.NewIoCScannerFromRules(rules, 5)
 			if err != nil {
 				log.Printf("Warning: Failed to create IoC scanner: %v", err)
+			} else {
+				iocScanner.Include = iocInclude
+				iocScanner.Exclude = iocExclude
+				iocScanner.MaxFileSize = maxFileSize
+				iocScanner.SkipNestedTargets = true
+				iocScanner.AllMatches = allMatches
+				iocScanner.MaxMatchesPerRule = maxMatchesPerRule
+				iocScanner.MaxMatchesPerFile = maxMatchesPerFile
+				iocScanner.MaxDecodeDepth = maxDecodeDepth
+				if obfuscation {
+					iocScanner.Obfuscation = scanner.NewObfuscationDetector()
+					iocScanner.Obfuscation.MinScore = obfuscationMinScore
+				}
+				if unicodeCheck {
+					iocScanner.Unicode = scanner.NewUnicodeDetector()
+				}
+				if binaries {
+					iocScanner.Binaries = scanner.NewBinaryDetector()
+				}
 			}
 
+			// Load known-malicious file hashes if provided
+			if iocScanner != nil && fileHashesPath != "" {
+				fileHashes, err := scanner.LoadFileHashes(fileHashesPath)
+				if err != nil {
+					log.Printf("Warning: Failed to load file hashes from %s: %v", fileHashesPath, err)
+				} else {
+					fmt.Printf("Loaded %d known-malicious file hashes\n", len(fileHashes.Entries))
+					iocScanner.FileHashes = fileHashes
+				}
+			}
+
+			// Extract network endpoints, matching them against IoCs if provided
+			if iocScanner != nil && network {
+				iocScanner.Network = scanner.NewNetworkDetector()
+				if networkIoCsPath != "" {
+					networkIoCs, err := scanner.LoadNetworkIoCs(networkIoCsPath)
+					if err != nil {
+						log.Printf("Warning: Failed to load network IoCs from %s: %v", networkIoCsPath, err)
+					} else {
+						fmt.Printf("Loaded %d network IoCs\n", len(networkIoCs.Entries))
+						iocScanner.Network.IoCs = networkIoCs
+					}
+				}
+			}
+
+			// Compare package names against popular packages
+			var typosquatDetector *scanner.TyposquatDetector
+			if typosquat {
+				typosquatDetector = scanner.NewTyposquatDetector(scanner.PopularPackages())
+			}
+
+			scriptAnalyzer := scanner.NewScriptAnalyzer()
+
 			// Scan all targets for packages and IoCs
 			allFindings := []scanner.Finding{}
 			packagesScanned := 0
@@ -84,6 +151,26 @@ func main() {
 						findings := blocklist.Match(pkg)
 						allFindings = append(allFindings, findings...)
 					}
+
+					// Check tarballs on disk against blocklisted integrity hashes
+					tarballFindings, err := blocklist.MatchTarballs(target.Path)
+					if err != nil {
+						log.Printf("Warning: Tarball hash check failed for %s: %v", target.Path, err)
+					} else {
+						allFindings = append(allFindings, tarballFindings...)
+					}
+				}
+
+				// Check install-time scripts
+				for _, pkg := range packages {
+					allFindings = append(allFindings, scriptAnalyzer.Analyze(pkg)...)
+				}
+
+				// Check package names for typosquatting
+				if typosquatDetector != nil {
+					for _, pkg := range packages {
+						allFindings = append(allFindings, typosquatDetector.Check(pkg)...)
+					}
 				}
 
 				// Run IoC scan on target
@@ -110,14 +197,37 @@ func main() {
 				fmt.Printf("\n⚠️  SECURITY ISSUES FOUND:\n\n")
 			}
 
+			// The endpoint and executable inventories list every package,
+			// suspicious or not
+			inventory := scanner.Inventory{Endpoints: []scanner.PackageEndpoints{}, Executables: []scanner.BinaryFile{}}
+			if iocScanner != nil {
+				inventory.Endpoints = iocScanner.Network.Endpoints()
+				inventory.Executables = iocScanner.Binaries.Files()
+			}
+
 			if outputFormat == "pretty" {
 				rw.WritePretty(allFindings)
+				rw.WriteEndpoints(inventory.Endpoints)
+				rw.WriteBinaries(inventory.Executables)
 			} else if outputFormat == "json" {
-				err := rw.WriteJSON(allFindings, "findings.json")
+				// findings.json stays an array of findings unless the
+				// inventory is asked for
+				var err error
+				if jsonInventory {
+					err = rw.WriteJSONReport(allFindings, inventory, "findings.json")
+				} else {
+					err = rw.WriteJSON(allFindings, "findings.json")
+				}
 				if err != nil {
 					log.Fatalf("Failed to write JSON output: %v", err)
 				}
 				fmt.Println("\nJSON report written to findings.json")
+			} else if outputFormat == "sarif" {
+				err := rw.WriteSARIFReport(allFindings, inventory, "findings.sarif")
+				if err != nil {
+					log.Fatalf("Failed to write SARIF output: %v", err)
+				}
+				fmt.Println("\nSARIF report written to findings.sarif")
 			} else {
 				log.Fatalf("Unsupported output format: %s", outputFormat)
 			}
@@ -132,10 +242,59 @@ func main() {
 	rootCmd.Flags().StringSliceVar(&paths, "paths", []string{"."}, "Paths to scan")
 	rootCmd.Flags().StringSliceVar(&exclude, "exclude", []string{}, "Exclude patterns (regex)")
 	rootCmd.Flags().StringVar(&outputFormat, "output", "pretty", "Output format (pretty, json, sarif)")
-	rootCmd.Flags().StringVar(&blocklistPath, "blocklist", "", "Path to blocklist JSON file")
+	rootCmd.Flags().BoolVar(&jsonInventory, "json-inventory", false, "With --output json, write an object holding the findings and the endpoint and executable inventories instead of an array of findings")
+	rootCmd.Flags().StringSliceVar(&blocklistPaths, "blocklist", []string{}, "Blocklist JSON files or directories (repeatable, merged)")
+	rootCmd.Flags().StringSliceVar(&rulesPaths, "rules", []string{}, "IoC rule JSON files or directories (repeatable); rules replace built-in rules with the same id")
+	rootCmd.Flags().BoolVar(&defaultRules, "default-rules", true, "Apply the built-in IoC rule pack")
+	rootCmd.Flags().StringSliceVar(&iocInclude, "ioc-include", scanner.DefaultIncludeGlobs, "Globs of files to scan for IoCs")
+	rootCmd.Flags().StringSliceVar(&iocExclude, "ioc-exclude", []string{}, "Globs of files and directories not to scan for IoCs")
+	rootCmd.Flags().Int64Var(&maxFileSize, "max-file-size", scanner.DefaultMaxFileSize, "Skip files larger than this many bytes during the IoC scan (0 for no limit)")
+	rootCmd.Flags().BoolVar(&allMatches, "all-matches", false, "Report every IoC match instead of the first match of each pattern per file")
+	rootCmd.Flags().IntVar(&maxMatchesPerRule, "max-matches-per-rule", scanner.DefaultMaxMatchesPerRule, "With --all-matches, maximum findings per pattern and file (0 for no limit)")
+	rootCmd.Flags().IntVar(&maxMatchesPerFile, "max-matches-per-file", scanner.DefaultMaxMatchesPerFile, "With --all-matches, maximum IoC findings per file (0 for no limit)")
+	rootCmd.Flags().IntVar(&maxDecodeDepth, "max-decode-depth", scanner.DefaultMaxDecodeDepth, "Nested base64, hex, charcode and escape encodings to decode and rescan (0 to disable)")
+	rootCmd.Flags().BoolVar(&obfuscation, "obfuscation", true, "Score scanned files for obfuscation and packed payloads")
+	rootCmd.Flags().Float64Var(&obfuscationMinScore, "obfuscation-min-score", scanner.DefaultObfuscationMinScore, "Obfuscation score (0 to 1) from which files are reported")
+	rootCmd.Flags().BoolVar(&unicodeCheck, "unicode", true, "Flag bidirectional control, invisible and look-alike characters in code and package.json")
+	rootCmd.Flags().BoolVar(&binaries, "binaries", true, "Identify ELF, PE and Mach-O executables and shell scripts in packages and flag unexpected ones")
+	rootCmd.Flags().BoolVar(&typosquat, "typosquat", false, "Flag package names imitating popular packages")
+	rootCmd.Flags().StringVar(&fileHashesPath, "file-hashes", "", "JSON or CSV list of SHA-256 hashes of known malicious files")
+	rootCmd.Flags().BoolVar(&network, "network", true, "Extract the URLs, IP addresses and hosts scanned files name and list them per package")
+	rootCmd.Flags().StringVar(&networkIoCsPath, "network-iocs", "", "JSON or CSV list of known malicious domains, IP addresses, CIDR ranges and URLs")
+	rootCmd.Flags().StringVar(&osvPath, "osv", "", "Path to a directory or zip of OSV advisories (npm ecosystem)")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// loadBlocklists loads and merges the blocklist files and OSV advisories,
+// skipping those that fail to load. It returns nil when none loaded.
+func loadBlocklists(blocklistPaths []string, osvPath string) *scanner.Blocklist {
+	var blocklist *scanner.Blocklist
+	for _, blocklistPath := range blocklistPaths {
+		loaded, err := scanner.LoadBlocklist(blocklistPath)
+		if err != nil {
+			log.Printf("Warning: Failed to load blocklist from %s: %v", blocklistPath, err)
+			continue
+		}
+		fmt.Printf("Loaded blocklist %s with %d entries\n", blocklistPath, len(loaded.Entries))
+		blocklist = scanner.MergeBlocklists(blocklist, loaded)
+	}
+
+	// Load OSV advisories if provided
+	if osvPath != "" {
+		advisories, err := scanner.LoadOSV(osvPath)
+		if err != nil {
+			log.Printf("Warning: Failed to load OSV advisories from %s: %v", osvPath, err)
+		} else {
+			fmt.Printf("Loaded %d OSV advisory entries\n", len(advisories.Entries))
+			blocklist = scanner.MergeBlocklists(blocklist, advisories)
+		}
+	}
+	if blocklist != nil && (len(blocklistPaths) > 1 || osvPath != "") {
+		fmt.Printf("Merged blocklist has %d entries\n", len(blocklist.Entries))
+	}
+	return blocklist
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBlocklists(t *testing.T) {
+	dir := t.TempDir()
+	missing := []string{filepath.Join(dir, "nonexist1"), filepath.Join(dir, "nonexist2")}
+
+	if blocklist := loadBlocklists(missing, ""); blocklist != nil {
+		t.Errorf("Expected no blocklist when every path fails, got %+v", blocklist)
+	}
+	if blocklist := loadBlocklists(missing, filepath.Join(dir, "osv")); blocklist != nil {
+		t.Errorf("Expected no blocklist when every path and the OSV path fail, got %+v", blocklist)
+	}
+
+	valid := filepath.Join(dir, "blocklist.json")
+	os.WriteFile(valid, []byte(`[{"name": "event-stream", "versions": ["3.3.6"]}]`), 0644)
+	blocklist := loadBlocklists(append(missing, valid), "")
+	if blocklist == nil || len(blocklist.Entries) != 1 {
+		t.Errorf("Expected the entry of %s, got %+v", valid, blocklist)
+	}
+}
@@ -1,8 +1,10 @@
 package scanner
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +34,98 @@ func BenchmarkIoCMatcher(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkIoCScan_MinifiedMatches scans a 900 KB one-line file matching a
+// default rule 60000 times, as a hostile package could ship to stall the scan.
+func BenchmarkIoCScan_MinifiedMatches(b *testing.B) {
+	dir := b.TempDir()
+	os.WriteFile(filepath.Join(dir, "bundle.js"), []byte(strings.Repeat("var a=crypto.x;", 60000)), 0644)
+
+	scanner, err := NewIoCScannerFromRules(DefaultRules(), 4)
+	if err != nil {
+		b.Fatalf("Failed to create IoC scanner: %v", err)
+	}
+
+	for _, allMatches := range []bool{false, true} {
+		b.Run(fmt.Sprintf("all-matches=%v", allMatches), func(b *testing.B) {
+			scanner.AllMatches = allMatches
+			for i := 0; i < b.N; i++ {
+				if _, err := scanner.Scan(dir); err != nil {
+					b.Fatalf("Failed to scan directory: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// syntheticBlocklist builds n entries mixing unscoped and scoped names,
+// exact versions, ranges and all-version entries.
+func syntheticBlocklist(n int) []BlocklistEntry {
+	entries := make([]BlocklistEntry, 0, n)
+	for i := 0; i < n; i++ {
+		entry := BlocklistEntry{Name: fmt.Sprintf("malicious-pkg-%d", i)}
+		switch i % 3 {
+		case 0:
+			entry.Versions = []string{"1.0.0", "1.0.1"}
+		case 1:
+			entry.Name = fmt.Sprintf("@evil-%d/pkg", i)
+			entry.Versions = []string{">=2.0.0 <2.3.0"}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// syntheticPackages builds a dependency tree of n packages, one in a hundred blocklisted.
+func syntheticPackages(n int) []PackageRef {
+	packages := make([]PackageRef, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("safe-pkg-%d", i)
+		if i%100 == 0 {
+			name = fmt.Sprintf("malicious-pkg-%d", i*3)
+		}
+		packages = append(packages, PackageRef{Name: name, Version: "1.0.0"})
+	}
+	return packages
+}
+
+// matchLinear is the former O(packages × entries) matcher, kept as a baseline.
+func matchLinear(entries []BlocklistEntry, pkg PackageRef) int {
+	count := 0
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name, pkg.Name) && (len(entry.Versions) == 0 || contains(entry.Versions, pkg.Version)) {
+			count++
+		}
+	}
+	return count
+}
+
+func BenchmarkBlocklistMatch(b *testing.B) {
+	entries := syntheticBlocklist(50000)
+	packages := syntheticPackages(3000)
+
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, pkg := range packages {
+				matchLinear(entries, pkg)
+			}
+		}
+	})
+
+	b.Run("indexed", func(b *testing.B) {
+		blocklist := newBlocklist(entries)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, pkg := range packages {
+				blocklist.Match(pkg)
+			}
+		}
+	})
+}
+
+func BenchmarkBlocklistIndex(b *testing.B) {
+	entries := syntheticBlocklist(50000)
+	for i := 0; i < b.N; i++ {
+		newBlocklist(entries)
+	}
+}
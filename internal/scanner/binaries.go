@@ -0,0 +1,294 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Executable formats recognized by their magic bytes.
+const (
+	FormatELF         = "ELF"
+	FormatPE          = "PE"
+	FormatMachO       = "Mach-O"
+	FormatMachOFat    = "Mach-O universal"
+	FormatShellScript = "shell script"
+)
+
+// binaryHeaderSize is the number of leading bytes read to identify a file.
+const binaryHeaderSize = 4096
+
+// maxFatArches is the most architectures a Mach-O universal binary is
+// expected to hold; Java class files share its magic with higher counts.
+const maxFatArches = 20
+
+// executableExtensions are the extensions native executables and libraries
+// are shipped with; other extensions disguise them.
+var executableExtensions = map[string]bool{
+	"": true, ".node": true, ".exe": true, ".dll": true, ".so": true, ".dylib": true, ".bin": true, ".out": true, ".o": true,
+}
+
+// shells are the interpreters of shell scripts.
+var shells = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true}
+
+// Architectures by ELF e_machine, PE machine and Mach-O CPU type.
+var (
+	elfMachines = map[uint16]string{
+		0x03: "x86", 0x08: "mips", 0x14: "ppc", 0x15: "ppc64", 0x16: "s390x", 0x28: "arm", 0x3e: "x86-64", 0xb7: "arm64", 0xf3: "riscv64", 0x102: "loong64",
+	}
+	peMachines = map[uint16]string{
+		0x014c: "x86", 0x8664: "x86-64", 0x01c0: "arm", 0x01c4: "arm", 0xaa64: "arm64",
+	}
+	machoCPUs = map[uint32]string{
+		7: "x86", 0x01000007: "x86-64", 12: "arm", 0x0100000c: "arm64", 18: "ppc", 0x01000012: "ppc64",
+	}
+)
+
+// BinaryFile is an executable found in a scanned package.
+type BinaryFile struct {
+	Name    string `json:"name"` // Package holding the file, if any
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	File    string `json:"file"`
+	Format  string `json:"format"`
+	Arch    string `json:"arch,omitempty"` // Comma-separated for universal binaries
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Flagged bool   `json:"flagged"`
+}
+
+// BinaryDetector identifies ELF, PE and Mach-O executables, including .node
+// addons, and shell scripts by their magic bytes, whatever their name. Every
+// one found is kept for Files, once per real path. Native executables are
+// reported when their package neither declares native code (see
+// PackageRef.Native) nor names the file in its scripts, "main" or "bin", and
+// when their extension disguises them as another type of file. Files outside
+// any package are only kept.
+type BinaryDetector struct {
+	files []BinaryFile
+	seen  map[string]int // Index in files by real path
+}
+
+// NewBinaryDetector creates a BinaryDetector.
+func NewBinaryDetector() *BinaryDetector {
+	return &BinaryDetector{seen: make(map[string]int)}
+}
+
+// Analyze identifies the file at path, which belongs to pkg, and reports it
+// when it is an unexpected executable.
+func (d *BinaryDetector) Analyze(pkg PackageRef, path string) []Finding {
+	findings, _ := d.analyze(pkg, path)
+	return findings
+}
+
+// analyze identifies a file and records it when it is an executable,
+// returning the findings and the file's SHA-256 (empty for other files); a
+// nil detector reports nothing.
+func (d *BinaryDetector) analyze(pkg PackageRef, path string) ([]Finding, string) {
+	findings := []Finding{}
+	if d == nil {
+		return findings, ""
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return findings, ""
+	}
+	defer file.Close()
+
+	header := make([]byte, binaryHeaderSize)
+	n, _ := io.ReadFull(file, header)
+	header = header[:n]
+	format, arch := identifyExecutable(header)
+	if format == "" && strings.HasSuffix(path, ".sh") {
+		format = FormatShellScript
+	}
+	if format == "" {
+		return findings, ""
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return findings, ""
+	}
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return findings, ""
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	record := BinaryFile{
+		Name:    pkg.Name,
+		Version: pkg.Version,
+		Path:    pkg.Path,
+		File:    path,
+		Format:  format,
+		Arch:    arch,
+		Size:    size,
+		SHA256:  sum,
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if strings.Contains(filepath.Base(path), ".so.") {
+		ext = ".so" // Versioned shared library
+	}
+	inPackage := format != FormatShellScript && pkg.Name != ""
+	disguised := inPackage && !executableExtensions[ext]
+	undeclared := inPackage && !pkg.Native && !referencedByPackage(pkg, path)
+	if disguised || undeclared {
+		record.Flagged = true
+
+		description := format + " executable"
+		if arch != "" {
+			description += " for " + arch
+		}
+		description += fmt.Sprintf(", %d bytes", size)
+
+		f := Finding{
+			Type:        "binary",
+			Name:        pkg.Name,
+			Version:     pkg.Version,
+			Path:        pkg.Path,
+			File:        path,
+			Reason:      "Package ships a native executable it neither declares nor references",
+			Description: description,
+			Evidence:    "sha256:" + sum,
+			Severity:    SeverityHigh,
+			RuleID:      "binary/undeclared",
+			Remediation: "Check where the executable comes from and what it does, e.g. by looking its hash up, and remove the package unless it is expected",
+		}
+		if disguised {
+			f.Reason = fmt.Sprintf("Native executable is disguised as a %s file", strings.TrimPrefix(ext, "."))
+			f.RuleID = "binary/disguised"
+		}
+		findings = append(findings, f)
+	}
+	d.record(record)
+	return findings, sum
+}
+
+// record adds a file to the inventory once, however many overlapping
+// targets reach it, preferring the record made within its package.
+func (d *BinaryDetector) record(file BinaryFile) {
+	real, err := filepath.EvalSymlinks(file.File)
+	if err != nil {
+		real = file.File
+	}
+	if d.seen == nil {
+		d.seen = make(map[string]int)
+	}
+	if i, ok := d.seen[real]; ok {
+		if d.files[i].Name == "" && file.Name != "" {
+			d.files[i] = file
+		}
+		return
+	}
+	d.seen[real] = len(d.files)
+	d.files = append(d.files, file)
+}
+
+// Files returns the executables found so far, sorted by path.
+func (d *BinaryDetector) Files() []BinaryFile {
+	files := []BinaryFile{}
+	if d == nil {
+		return files
+	}
+	files = append(files, d.files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+	return files
+}
+
+// identifyExecutable returns the format and architecture of an executable
+// from the leading bytes of its file, or an empty format for other files.
+func identifyExecutable(header []byte) (format, arch string) {
+	switch {
+	case bytes.HasPrefix(header, []byte("\x7fELF")) && len(header) >= 20:
+		var order binary.ByteOrder = binary.LittleEndian
+		if header[5] == 2 {
+			order = binary.BigEndian
+		}
+		return FormatELF, machineName(elfMachines, order.Uint16(header[18:20]))
+
+	case bytes.HasPrefix(header, []byte("MZ")) && len(header) >= 0x40:
+		offset := int(binary.LittleEndian.Uint32(header[0x3c:0x40]))
+		if offset+6 > len(header) || !bytes.Equal(header[offset:offset+4], []byte("PE\x00\x00")) {
+			return "", ""
+		}
+		return FormatPE, machineName(peMachines, binary.LittleEndian.Uint16(header[offset+4:offset+6]))
+
+	case len(header) >= 8 && (bytes.HasPrefix(header, []byte{0xfe, 0xed, 0xfa, 0xce}) || bytes.HasPrefix(header, []byte{0xfe, 0xed, 0xfa, 0xcf})):
+		return FormatMachO, machineName(machoCPUs, binary.BigEndian.Uint32(header[4:8]))
+
+	case len(header) >= 8 && (bytes.HasPrefix(header, []byte{0xce, 0xfa, 0xed, 0xfe}) || bytes.HasPrefix(header, []byte{0xcf, 0xfa, 0xed, 0xfe})):
+		return FormatMachO, machineName(machoCPUs, binary.LittleEndian.Uint32(header[4:8]))
+
+	case bytes.HasPrefix(header, []byte{0xca, 0xfe, 0xba, 0xbe}) && len(header) >= 8:
+		count := binary.BigEndian.Uint32(header[4:8])
+		if count == 0 || count > maxFatArches {
+			return "", "" // A Java class file
+		}
+		arches := []string{}
+		for i := 0; i < int(count) && 8+20*i+4 <= len(header); i++ {
+			arches = append(arches, machineName(machoCPUs, binary.BigEndian.Uint32(header[8+20*i:])))
+		}
+		return FormatMachOFat, strings.Join(arches, ",")
+
+	case bytes.HasPrefix(header, []byte("#!")):
+		line, _, _ := bufio.NewReader(bytes.NewReader(header[2:])).ReadLine()
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 {
+			return "", ""
+		}
+		interpreter := filepath.Base(fields[0])
+		if interpreter == "env" {
+			interpreter = ""
+			for _, field := range fields[1:] {
+				if !strings.HasPrefix(field, "-") {
+					interpreter = filepath.Base(field)
+					break
+				}
+			}
+		}
+		if shells[interpreter] {
+			return FormatShellScript, ""
+		}
+	}
+	return "", ""
+}
+
+// machineName names an architecture code, or formats unknown ones in hex.
+func machineName[K uint16 | uint32](names map[K]string, code K) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", code)
+}
+
+// referencedByPackage reports whether the package.json of pkg names the file
+// at path in any of its scripts, or as its "main" module or a "bin" command.
+func referencedByPackage(pkg PackageRef, path string) bool {
+	for _, file := range referencedFiles(pkg) {
+		if filepath.Clean(file) == filepath.Clean(path) {
+			return true
+		}
+	}
+	rel, err := filepath.Rel(pkg.Path, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, script := range pkg.Scripts {
+		if strings.Contains(script, rel) {
+			return true
+		}
+	}
+	return false
+}
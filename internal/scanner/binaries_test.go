@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// elfHeader returns the start of a 64-bit little-endian ELF file for machine.
+func elfHeader(machine uint16) []byte {
+	header := make([]byte, 64)
+	copy(header, "\x7fELF\x02\x01\x01")
+	binary.LittleEndian.PutUint16(header[16:], 2) // ET_EXEC
+	binary.LittleEndian.PutUint16(header[18:], machine)
+	return header
+}
+
+// peHeader returns the start of a PE file for machine.
+func peHeader(machine uint16) []byte {
+	header := make([]byte, 0x90)
+	copy(header, "MZ")
+	binary.LittleEndian.PutUint32(header[0x3c:], 0x80)
+	copy(header[0x80:], "PE\x00\x00")
+	binary.LittleEndian.PutUint16(header[0x84:], machine)
+	return header
+}
+
+func TestIdentifyExecutable(t *testing.T) {
+	macho := make([]byte, 32)
+	copy(macho, []byte{0xcf, 0xfa, 0xed, 0xfe})
+	binary.LittleEndian.PutUint32(macho[4:], 0x0100000c)
+
+	fat := make([]byte, 48)
+	copy(fat, []byte{0xca, 0xfe, 0xba, 0xbe})
+	binary.BigEndian.PutUint32(fat[4:], 2)
+	binary.BigEndian.PutUint32(fat[8:], 0x01000007)
+	binary.BigEndian.PutUint32(fat[28:], 0x0100000c)
+
+	bigEndianELF := make([]byte, 64)
+	copy(bigEndianELF, "\x7fELF\x02\x02\x01")
+	binary.BigEndian.PutUint16(bigEndianELF[18:], 0x16)
+
+	tests := []struct {
+		name   string
+		header []byte
+		format string
+		arch   string
+	}{
+		{"elf", elfHeader(0x3e), FormatELF, "x86-64"},
+		{"big-endian elf", bigEndianELF, FormatELF, "s390x"},
+		{"unknown machine", elfHeader(0x1234), FormatELF, "0x1234"},
+		{"pe", peHeader(0xaa64), FormatPE, "arm64"},
+		{"mach-o", macho, FormatMachO, "arm64"},
+		{"universal", fat, FormatMachOFat, "x86-64,arm64"},
+		{"java class", []byte{0xca, 0xfe, 0xba, 0xbe, 0x00, 0x00, 0x00, 0x34}, "", ""},
+		{"dos stub only", append([]byte("MZ"), make([]byte, 0x40)...), "", ""},
+		{"shell", []byte("#!/bin/sh\ncurl x | sh\n"), FormatShellScript, ""},
+		{"env bash", []byte("#!/usr/bin/env -S bash -e\n"), FormatShellScript, ""},
+		{"node script", []byte("#!/usr/bin/env node\nrequire('./cli')\n"), "", ""},
+		{"text", []byte("MZ is not a PE file"), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, arch := identifyExecutable(tt.header)
+			if format != tt.format || arch != tt.arch {
+				t.Errorf("Expected %q %q, got %q %q", tt.format, tt.arch, format, arch)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_Binaries(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel string, content []byte) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, content, 0644)
+	}
+	elf := elfHeader(0x3e)
+
+	write("node_modules/addon/package.json", []byte(`{"name": "addon", "version": "1.0.0", "gypfile": true}`))
+	write("node_modules/addon/build/Release/addon.node", elf)
+	write("node_modules/addon/assets/logo.png", elf)
+	write("node_modules/tool/package.json", []byte(`{"name": "tool", "version": "2.0.0", "bin": {"tool": "bin/tool"}, "scripts": {"postinstall": "./vendor/helper --init"}}`))
+	write("node_modules/tool/bin/tool", elf)
+	write("node_modules/tool/vendor/helper", peHeader(0x8664))
+	write("node_modules/tool/scripts/build.sh", []byte("echo building\n"))
+	write("node_modules/utils/package.json", []byte(`{"name": "utils", "version": "3.0.0"}`))
+	write("node_modules/utils/lib/update", elf)
+	write("node_modules/utils/lib/index.js", []byte("module.exports = {}\n"))
+	write("assets/logo.png", elf)
+
+	sum := sha256.Sum256(elf)
+	s, err := NewIoCScanner(nil, 100)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	s.Binaries = NewBinaryDetector()
+	s.FileHashes = newFileHashDB([]FileHashIoC{{SHA256: hex.EncodeToString(sum[:]), Campaign: "Dropper"}})
+
+	findings, err := s.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := map[string]Finding{}
+	hashMatches := 0
+	for _, f := range findings {
+		switch f.Type {
+		case "binary":
+			got[filepath.ToSlash(f.File[len(root)+1:])] = f
+		case "filehash":
+			hashMatches++
+		default:
+			t.Errorf("Unexpected finding %+v", f)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 binary findings, got %+v", got)
+	}
+	if f := got["node_modules/addon/assets/logo.png"]; f.RuleID != "binary/disguised" || f.Name != "addon" {
+		t.Errorf("Expected the disguised executable in addon, got %+v", f)
+	}
+	if f := got["node_modules/utils/lib/update"]; f.RuleID != "binary/undeclared" || f.Description != "ELF executable for x86-64, 64 bytes" || f.Evidence != "sha256:"+hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the undeclared executable in utils, got %+v", f)
+	}
+	if hashMatches != 5 {
+		t.Errorf("Expected the 5 copies of the ELF file to match the hash list, got %d", hashMatches)
+	}
+
+	files := s.Binaries.Files()
+	if len(files) != 7 {
+		t.Fatalf("Expected 7 executables, got %+v", files)
+	}
+	flagged := 0
+	for _, file := range files {
+		if file.Flagged {
+			flagged++
+		}
+		if filepath.Base(file.File) == "build.sh" && (file.Format != FormatShellScript || file.Name != "tool" || file.Size != 14) {
+			t.Errorf("Expected the shell script of tool, got %+v", file)
+		}
+		if file.File == filepath.Join(root, "assets", "logo.png") && (file.Name != "" || file.Flagged) {
+			t.Errorf("Expected the executable outside any package kept but not flagged, got %+v", file)
+		}
+		if filepath.Base(file.File) == "helper" && (file.Format != FormatPE || file.Arch != "x86-64") {
+			t.Errorf("Expected a PE helper for x86-64, got %+v", file)
+		}
+	}
+	if flagged != 2 {
+		t.Errorf("Expected 2 flagged executables, got %d", flagged)
+	}
+}
+
+func TestBinaryDetector_FilesOncePerRealPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "update")
+	os.WriteFile(path, elfHeader(0x3e), 0755)
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("Symlinks unsupported: %v", err)
+	}
+
+	d := NewBinaryDetector()
+	d.Analyze(PackageRef{Path: dir}, path)
+	d.Analyze(PackageRef{Name: "utils", Version: "3.0.0", Path: dir}, path)
+	d.Analyze(PackageRef{Name: "utils", Version: "3.0.0", Path: dir}, link)
+
+	files := d.Files()
+	if len(files) != 1 || files[0].Name != "utils" || !files[0].Flagged {
+		t.Errorf("Expected the executable once, recorded within utils, got %+v", files)
+	}
+}
@@ -2,23 +2,193 @@ package scanner
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // BlocklistEntry represents a blocklist entry with name and versions.
+// Versions may be exact versions or npm semver ranges such as ">=4.0.0 <4.1.3",
+// "^1.2.0" or "1.x || 2.0.0"; an empty list blocks every version.
+// Integrity lists tarball hashes (SRI strings such as "sha512-..." or hex
+// SHA-1 shasums) that are malicious whatever name they are published under;
+// Name may be empty for such entries.
+// The remaining fields optionally describe the advisory behind the entry and
+// are copied into the findings it produces.
 type BlocklistEntry struct {
-	Name     string   `json:"name"`
-	Versions []string `json:"versions"`
+	Name        string   `json:"name"`
+	Versions    []string `json:"versions"`
+	Integrity   []string `json:"integrity,omitempty"`
+	ID          string   `json:"id,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Severity    string   `json:"severity,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	References  []string `json:"references,omitempty"`
+	Published   string   `json:"published,omitempty"`
+	SafeVersion string   `json:"safeVersion,omitempty"`
+
+	// Sources lists the files the entry was loaded from; it has several
+	// items when identical entries were merged from different blocklists.
+	Sources []string `json:"-"`
 }
 
 // Blocklist represents a collection of blocklist entries.
+// Lookups go through an index of normalized names that is built on load, or
+// on first use for a Blocklist built by hand. Callers that change Entries
+// afterwards must call Reindex before the next lookup.
 type Blocklist struct {
 	Entries []BlocklistEntry
+
+	indexOnce      sync.Once
+	index          map[string][]versionMatcher
+	integrityIndex map[string][]int // normalized hash -> indexes into Entries
+}
+
+// versionMatcher holds the precompiled versions of one blocklist entry.
+type versionMatcher struct {
+	entry  int // index into Entries
+	all    bool
+	exact  map[string]bool
+	ranges []semverRange
+}
+
+// newBlocklist creates an indexed blocklist from entries.
+func newBlocklist(entries []BlocklistEntry) *Blocklist {
+	b := &Blocklist{Entries: entries}
+	b.indexOnce.Do(b.buildIndex)
+	return b
+}
+
+// Reindex rebuilds the lookup index after Entries has been modified. It must
+// not run concurrently with lookups.
+func (b *Blocklist) Reindex() {
+	b.indexOnce.Do(func() {})
+	b.buildIndex()
+}
+
+// ensureIndex builds the index of a Blocklist that was not created by a
+// loader. It is safe for concurrent use.
+func (b *Blocklist) ensureIndex() {
+	b.indexOnce.Do(b.buildIndex)
+}
+
+// buildIndex groups entries by normalized name and precompiles their versions.
+func (b *Blocklist) buildIndex() {
+	b.index = make(map[string][]versionMatcher, len(b.Entries))
+	b.integrityIndex = map[string][]int{}
+	for i, entry := range b.Entries {
+		for _, value := range entry.Integrity {
+			if h := normalizeIntegrity(value); h != "" {
+				b.integrityIndex[h] = append(b.integrityIndex[h], i)
+			}
+		}
+		if strings.TrimSpace(entry.Name) == "" {
+			continue // Hash-only entry
+		}
+
+		m := versionMatcher{entry: i, all: len(entry.Versions) == 0, exact: map[string]bool{}}
+		for _, spec := range entry.Versions {
+			m.exact[withoutBuild(spec)] = true
+			if isPlainVersion(spec) {
+				continue // Fully handled by the exact lookup
+			}
+			if r, err := parseSemverRange(spec); err == nil {
+				m.ranges = append(m.ranges, r)
+			}
+		}
+		key := normalizePackageName(entry.Name)
+		b.index[key] = append(b.index[key], m)
+	}
+}
+
+// matches reports whether a version is blocked by the entry. parsed is the
+// package version parsed as semver, or nil if it is not a valid version.
+func (m versionMatcher) matches(version string, parsed *semver) bool {
+	if m.all || m.exact[withoutBuild(version)] {
+		return true
+	}
+	if parsed == nil {
+		return false
+	}
+	for _, r := range m.ranges {
+		if r.satisfies(*parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPlainVersion reports whether spec is a bare "major.minor.patch" version.
+func isPlainVersion(spec string) bool {
+	dots := 0
+	for i := 0; i < len(spec); i++ {
+		switch {
+		case spec[i] == '.':
+			dots++
+		case spec[i] < '0' || spec[i] > '9':
+			return false
+		}
+	}
+	return dots == 2
 }
 
-// LoadBlocklist loads a blocklist from a file.
+// withoutBuild strips "+build" metadata, which semver ignores when comparing
+// versions, so "1.0.0" and "1.0.0+build" share an exact lookup key.
+func withoutBuild(version string) string {
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		return version[:i]
+	}
+	return version
+}
+
+// normalizePackageName lowercases a package name and decodes the URL-encoded
+// scope separator ("@scope%2fname") so equivalent spellings share an index key.
+func normalizePackageName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.HasPrefix(name, "@") {
+		name = strings.Replace(name, "%2f", "/", 1)
+	}
+	return name
+}
+
+// LoadBlocklist loads a blocklist from a JSON file, or from every JSON file
+// under a directory. Each entry remembers the file it was read from.
 func LoadBlocklist(path string) (*Blocklist, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		entries, err := loadBlocklistFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return newBlocklist(entries), nil
+	}
+
+	merged := newBlocklist(nil)
+	err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || !strings.HasSuffix(p, ".json") {
+			return err
+		}
+		entries, err := loadBlocklistFile(p)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		merged = MergeBlocklists(merged, &Blocklist{Entries: entries})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// loadBlocklistFile decodes a JSON array of entries and records its path as their source.
+func loadBlocklistFile(path string) ([]BlocklistEntry, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -29,29 +199,103 @@ func LoadBlocklist(path string) (*Blocklist, error) {
 	if err := json.NewDecoder(file).Decode(&entries); err != nil {
 		return nil, err
 	}
+	for i := range entries {
+		entries[i].Sources = []string{path}
+	}
+	return entries, nil
+}
+
+// MergeBlocklists combines blocklists into one. Entries naming the same
+// package, versions and advisory are kept once, with their sources combined.
+// Nil blocklists are ignored.
+func MergeBlocklists(lists ...*Blocklist) *Blocklist {
+	entries := []BlocklistEntry{}
+	seen := map[string]int{}
 
-	return &Blocklist{Entries: entries}, nil
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, entry := range list.Entries {
+			key := entryKey(entry)
+			if i, ok := seen[key]; ok {
+				for _, source := range entry.Sources {
+					if !contains(entries[i].Sources, source) {
+						entries[i].Sources = append(entries[i].Sources, source)
+					}
+				}
+				continue
+			}
+			seen[key] = len(entries)
+			entry.Sources = append([]string(nil), entry.Sources...)
+			entries = append(entries, entry)
+		}
+	}
+
+	return newBlocklist(entries)
+}
+
+// entryKey identifies entries that block the same versions for the same advisory.
+func entryKey(e BlocklistEntry) string {
+	versions := append([]string(nil), e.Versions...)
+	sort.Strings(versions)
+	hashes := append([]string(nil), e.Integrity...)
+	sort.Strings(hashes)
+	return normalizePackageName(e.Name) + "\x00" + e.ID + "\x00" + strings.Join(versions, "||") + "\x00" + strings.Join(hashes, " ")
 }
 
 // Match checks if a package matches the blocklist.
 func (b *Blocklist) Match(pkg PackageRef) []Finding {
 	findings := []Finding{}
 
-	for _, entry := range b.Entries {
-		if strings.EqualFold(entry.Name, pkg.Name) {
-			if len(entry.Versions) == 0 || contains(entry.Versions, pkg.Version) {
-				findings = append(findings, Finding{
-					Type:    "blocklist",
-					Name:    pkg.Name,
-					Version: pkg.Version,
-					Path:    pkg.Path,
-					Reason:  "Matched blocklist",
-				})
+	b.ensureIndex()
+
+	matched := map[int]bool{}
+	matchers := b.index[normalizePackageName(pkg.Name)]
+	if len(matchers) > 0 {
+		var parsed *semver
+		if v, err := parseSemver(pkg.Version); err == nil {
+			parsed = &v
+		}
+
+		for _, m := range matchers {
+			if m.matches(pkg.Version, parsed) {
+				matched[m.entry] = true
+				findings = append(findings, b.Entries[m.entry].finding(pkg))
 			}
 		}
 	}
 
-	return findings
+	return append(findings, b.matchIntegrity(pkg, matched)...)
+}
+
+// finding builds the finding reported when pkg matches the entry.
+func (e BlocklistEntry) finding(pkg PackageRef) Finding {
+	ruleID := e.ID
+	if ruleID == "" {
+		ruleID = "blocklist/" + strings.ToLower(e.Name)
+	}
+
+	remediation := fmt.Sprintf("Remove %s from the dependency tree, reinstall from a clean lockfile and rotate any credentials available to the install", pkg.Name)
+	if e.SafeVersion != "" {
+		remediation = fmt.Sprintf("Upgrade %s to %s or later and rotate any credentials available to the install", pkg.Name, e.SafeVersion)
+	}
+
+	return Finding{
+		Type:        "blocklist",
+		Name:        pkg.Name,
+		Version:     pkg.Version,
+		Path:        pkg.Path,
+		File:        pkg.Lockfile,
+		Reason:      "Matched blocklist",
+		Evidence:    e.Summary,
+		Advisory:    e.ID,
+		Severity:    normalizeSeverity(e.Severity, SeverityCritical),
+		RuleID:      ruleID,
+		References:  e.References,
+		Remediation: remediation,
+		Source:      strings.Join(e.Sources, ", "),
+	}
 }
 
 // contains checks if a slice contains a string.
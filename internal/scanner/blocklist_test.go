@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -72,6 +73,7 @@ func TestBlocklist_Match(t *testing.T) {
 			{Name: "malicious-package", Versions: []string{"1.0.0", "1.0.1"}},
 			{Name: "evil-package", Versions: []string{}}, // All versions
 			{Name: "case-test", Versions: []string{"2.0.0"}},
+			{Name: "ranged-package", Versions: []string{">=4.0.0 <4.1.3", "1.x"}},
 		},
 	}
 
@@ -110,6 +112,23 @@ func TestBlocklist_Match(t *testing.T) {
 			expectedCount:  1,
 			expectedReason: "Matched blocklist",
 		},
+		{
+			name:           "match version inside range",
+			pkg:            PackageRef{Name: "ranged-package", Version: "4.1.2", Path: "/test/path"},
+			expectedCount:  1,
+			expectedReason: "Matched blocklist",
+		},
+		{
+			name:           "match version inside x-range",
+			pkg:            PackageRef{Name: "ranged-package", Version: "1.9.0", Path: "/test/path"},
+			expectedCount:  1,
+			expectedReason: "Matched blocklist",
+		},
+		{
+			name:          "version outside ranges",
+			pkg:           PackageRef{Name: "ranged-package", Version: "4.1.3", Path: "/test/path"},
+			expectedCount: 0,
+		},
 		{
 			name:          "no match - different package",
 			pkg:           PackageRef{Name: "safe-package", Version: "1.0.0", Path: "/test/path"},
@@ -190,4 +209,141 @@ func TestContains(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestBlocklist_MatchMetadata(t *testing.T) {
+	blocklist := &Blocklist{
+		Entries: []BlocklistEntry{
+			{
+				Name:        "ua-parser-js",
+				Versions:    []string{"0.7.29"},
+				ID:          "GHSA-pjwm-rvh2-c87w",
+				Severity:    "HIGH",
+				Summary:     "Embedded malware in ua-parser-js",
+				References:  []string{"https://github.com/advisories/GHSA-pjwm-rvh2-c87w"},
+				Published:   "2021-10-22T20:38:16Z",
+				SafeVersion: "0.7.30",
+			},
+			{Name: "flatmap-stream"},
+		},
+	}
+
+	findings := blocklist.Match(PackageRef{Name: "ua-parser-js", Version: "0.7.29"})
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d", len(findings))
+	}
+
+	f := findings[0]
+	if f.Severity != SeverityHigh || f.RuleID != "GHSA-pjwm-rvh2-c87w" || f.Advisory != "GHSA-pjwm-rvh2-c87w" {
+		t.Errorf("Unexpected advisory metadata: %+v", f)
+	}
+	if len(f.References) != 1 || !strings.Contains(f.Remediation, "0.7.30") {
+		t.Errorf("Expected references and an upgrade remediation, got %+v", f)
+	}
+
+	findings = blocklist.Match(PackageRef{Name: "flatmap-stream", Version: "0.1.1"})
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d", len(findings))
+	}
+	f = findings[0]
+	if f.Severity != SeverityCritical || f.RuleID != "blocklist/flatmap-stream" || !strings.Contains(f.Remediation, "Remove flatmap-stream") {
+		t.Errorf("Unexpected defaults for an entry without metadata: %+v", f)
+	}
+}
+
+func TestBlocklist_Index(t *testing.T) {
+	blocklist := newBlocklist([]BlocklistEntry{
+		{Name: "@Evil/Pkg", Versions: []string{"1.0.0"}},
+		{Name: "pkg", Versions: []string{"2.0.0"}},
+	})
+
+	tests := []struct {
+		name          string
+		pkg           PackageRef
+		expectedCount int
+	}{
+		{"scoped name is case-insensitive", PackageRef{Name: "@evil/pkg", Version: "1.0.0"}, 1},
+		{"encoded scope separator", PackageRef{Name: "@evil%2Fpkg", Version: "1.0.0"}, 1},
+		{"scope does not match unscoped name", PackageRef{Name: "pkg", Version: "1.0.0"}, 0},
+		{"unscoped name", PackageRef{Name: "PKG", Version: "2.0.0"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(blocklist.Match(tt.pkg)); got != tt.expectedCount {
+				t.Errorf("Expected %d findings, got %d", tt.expectedCount, got)
+			}
+		})
+	}
+
+	// Entries appended after loading are picked up once the list is reindexed.
+	blocklist.Entries = append(blocklist.Entries, BlocklistEntry{Name: "late-addition"})
+	blocklist.Reindex()
+	if got := len(blocklist.Match(PackageRef{Name: "late-addition", Version: "1.0.0"})); got != 1 {
+		t.Errorf("Expected appended entry to match, got %d findings", got)
+	}
+}
+
+func TestLoadBlocklist_Directory(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "feeds"), 0755)
+	internal := filepath.Join(dir, "internal.json")
+	public := filepath.Join(dir, "feeds", "public.json")
+	os.WriteFile(internal, []byte(`[{"name": "corp-typo", "versions": []}, {"name": "event-stream", "versions": ["3.3.6"]}]`), 0644)
+	os.WriteFile(public, []byte(`[{"name": "Event-Stream", "versions": ["3.3.6"]}, {"name": "flatmap-stream", "versions": []}]`), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+	blocklist, err := LoadBlocklist(dir)
+	if err != nil {
+		t.Fatalf("LoadBlocklist failed: %v", err)
+	}
+
+	if len(blocklist.Entries) != 3 {
+		t.Fatalf("Expected 3 de-duplicated entries, got %d: %+v", len(blocklist.Entries), blocklist.Entries)
+	}
+
+	findings := blocklist.Match(PackageRef{Name: "event-stream", Version: "3.3.6"})
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding for the overlapping entry, got %d", len(findings))
+	}
+	if !strings.Contains(findings[0].Source, internal) || !strings.Contains(findings[0].Source, public) {
+		t.Errorf("Expected both sources in %q", findings[0].Source)
+	}
+
+	findings = blocklist.Match(PackageRef{Name: "corp-typo", Version: "1.0.0"})
+	if len(findings) != 1 || findings[0].Source != internal {
+		t.Errorf("Expected finding sourced from %s, got %+v", internal, findings)
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644)
+	if _, err := LoadBlocklist(dir); err == nil {
+		t.Error("Expected error for a malformed file in the directory, got nil")
+	}
+}
+
+func TestMergeBlocklists(t *testing.T) {
+	a := &Blocklist{Entries: []BlocklistEntry{
+		{Name: "pkg", Versions: []string{"1.0.0", "2.0.0"}, Sources: []string{"a.json"}},
+		{Name: "other", Sources: []string{"a.json"}},
+	}}
+	b := &Blocklist{Entries: []BlocklistEntry{
+		{Name: "PKG", Versions: []string{"2.0.0", "1.0.0"}, Sources: []string{"b.json"}},
+		{Name: "pkg", Versions: []string{"1.0.0"}, ID: "MAL-1", Sources: []string{"b.json"}},
+	}}
+
+	merged := MergeBlocklists(a, nil, b)
+	if len(merged.Entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(merged.Entries), merged.Entries)
+	}
+	if got := merged.Entries[0].Sources; len(got) != 2 || got[0] != "a.json" || got[1] != "b.json" {
+		t.Errorf("Expected merged sources [a.json b.json], got %v", got)
+	}
+	if len(a.Entries[0].Sources) != 1 {
+		t.Error("Merging must not modify the input blocklists")
+	}
+
+	// Distinct advisories for the same version are both reported.
+	if got := len(merged.Match(PackageRef{Name: "pkg", Version: "1.0.0"})); got != 2 {
+		t.Errorf("Expected 2 findings, got %d", got)
+	}
+}
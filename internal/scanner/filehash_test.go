@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFileHashes(t *testing.T) {
+	sum := sha256.Sum256([]byte("stealer"))
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		expected    FileHashIoC
+		expectError bool
+	}{
+		{
+			name:     "json",
+			file:     "hashes.json",
+			content:  `[{"sha256": "` + hash + `", "campaign": "Shai-Hulud", "severity": "high", "references": ["https://example.com"]}]`,
+			expected: FileHashIoC{SHA256: hash, Campaign: "Shai-Hulud", Severity: "high", References: []string{"https://example.com"}},
+		},
+		{
+			name:     "csv with header and comments",
+			file:     "hashes.csv",
+			content:  "sha256,campaign,severity\n# bundle.js payload\n" + hash + ", Shai-Hulud\n",
+			expected: FileHashIoC{SHA256: hash, Campaign: "Shai-Hulud"},
+		},
+		{
+			name:     "uppercase hash",
+			file:     "upper.csv",
+			content:  "AAAA" + hash[4:] + ",Campaign\n",
+			expected: FileHashIoC{SHA256: "aaaa" + hash[4:], Campaign: "Campaign"},
+		},
+		{
+			name:        "invalid hash",
+			file:        "invalid.csv",
+			content:     "deadbeef,Campaign\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid json",
+			file:        "invalid.json",
+			content:     `{"sha256": "x"}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			db, err := LoadFileHashes(path)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(db.Entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(db.Entries))
+			}
+
+			entry := db.Entries[0]
+			if entry.Source != path {
+				t.Errorf("Expected source %s, got %s", path, entry.Source)
+			}
+			if entry.SHA256 != tt.expected.SHA256 || entry.Campaign != tt.expected.Campaign || entry.Severity != tt.expected.Severity || len(entry.References) != len(tt.expected.References) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, entry)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_FileHashes(t *testing.T) {
+	tempDir := t.TempDir()
+	payload := []byte("const stolen = 'payload'")
+	os.WriteFile(filepath.Join(tempDir, "bundle.js"), payload, 0644)
+	os.WriteFile(filepath.Join(tempDir, "index.js"), []byte("module.exports = 1"), 0644)
+
+	sum := sha256.Sum256(payload)
+	scanner, err := NewIoCScanner(nil, 10)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	scanner.FileHashes = newFileHashDB([]FileHashIoC{{SHA256: hex.EncodeToString(sum[:]), Campaign: "Shai-Hulud", Source: "hashes.csv"}})
+
+	findings, err := scanner.Scan(tempDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d: %+v", len(findings), findings)
+	}
+
+	f := findings[0]
+	if f.Type != "filehash" || f.File != filepath.Join(tempDir, "bundle.js") {
+		t.Errorf("Unexpected finding: %+v", f)
+	}
+	if f.Campaign != "Shai-Hulud" || f.Evidence != "sha256:"+hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected campaign and hash evidence, got %+v", f)
+	}
+	if f.Severity != SeverityCritical || f.Source != "hashes.csv" {
+		t.Errorf("Expected critical finding from hashes.csv, got %+v", f)
+	}
+}
@@ -0,0 +1,171 @@
+package scanner
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shasumPattern matches a hex SHA-1, as in package.json "dist.shasum" and the
+// "#<sha1>" fragment Yarn appends to resolved URLs.
+var shasumPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// normalizeIntegrity converts an SRI string ("sha512-<base64>") or a hex
+// SHA-1 shasum into "<algorithm>:<hex digest>" so both spellings of the same
+// hash compare equal. It returns "" for values it does not recognize.
+func normalizeIntegrity(value string) string {
+	value = strings.TrimSpace(value)
+	if shasumPattern.MatchString(value) {
+		return "sha1:" + strings.ToLower(value)
+	}
+
+	algo, digest, ok := strings.Cut(value, "-")
+	if !ok {
+		return ""
+	}
+	algo = strings.ToLower(algo)
+	if algo != "sha1" && algo != "sha256" && algo != "sha384" && algo != "sha512" {
+		return ""
+	}
+	if i := strings.IndexByte(digest, '?'); i >= 0 {
+		digest = digest[:i] // SRI options
+	}
+	raw, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil {
+		return ""
+	}
+	return algo + ":" + hex.EncodeToString(raw)
+}
+
+// packageIntegrities returns the normalized hashes recorded for a package:
+// every SRI in its integrity field and the shasum in a Yarn resolved URL.
+func packageIntegrities(pkg PackageRef) []string {
+	hashes := []string{}
+	for _, sri := range strings.Fields(pkg.Integrity) {
+		if h := normalizeIntegrity(sri); h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+	if _, fragment, ok := strings.Cut(pkg.Resolved, "#"); ok {
+		if h := normalizeIntegrity(fragment); h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
+// matchIntegrity reports the entries whose tarball hashes are recorded for
+// pkg, skipping entries in skip that already matched by name.
+func (b *Blocklist) matchIntegrity(pkg PackageRef, skip map[int]bool) []Finding {
+	findings := []Finding{}
+	if len(b.integrityIndex) == 0 {
+		return findings
+	}
+
+	reported := map[int]bool{}
+	for _, h := range packageIntegrities(pkg) {
+		for _, i := range b.integrityIndex[h] {
+			if skip[i] || reported[i] {
+				continue
+			}
+			reported[i] = true
+			findings = append(findings, b.Entries[i].integrityFinding(pkg, h))
+		}
+	}
+	return findings
+}
+
+// MatchTarballs hashes the package tarballs (*.tgz, *.tar.gz) under root and
+// reports those whose SHA-512, SHA-256 or SHA-1 is blocklisted. Nested
+// projects that Discover returns as targets of their own are left to their
+// own call, so each tarball is reported once.
+func (b *Blocklist) MatchTarballs(root string) ([]Finding, error) {
+	findings := []Finding{}
+	b.ensureIndex()
+	if len(b.integrityIndex) == 0 {
+		return findings, nil
+	}
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip paths with errors
+		}
+		if info.IsDir() {
+			if nestedTarget(root, p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !(strings.HasSuffix(p, ".tgz") || strings.HasSuffix(p, ".tar.gz")) {
+			return nil
+		}
+
+		hashes, err := hashFile(p)
+		if err != nil {
+			return nil
+		}
+		for _, h := range hashes {
+			for _, i := range b.integrityIndex[h] {
+				f := b.Entries[i].integrityFinding(PackageRef{Path: filepath.Dir(p)}, h)
+				f.File = p
+				f.Remediation = "Delete " + p + ", remove whatever installed it and rotate any credentials available to the install"
+				findings = append(findings, f)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// hashFile returns the normalized SHA-512, SHA-256 and SHA-1 of a file.
+func hashFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	s512, s256, s1 := sha512.New(), sha256.New(), sha1.New()
+	if _, err := io.Copy(io.MultiWriter(s512, s256, s1), file); err != nil {
+		return nil, err
+	}
+
+	return []string{
+		"sha512:" + hex.EncodeToString(s512.Sum(nil)),
+		"sha256:" + hex.EncodeToString(s256.Sum(nil)),
+		"sha1:" + hex.EncodeToString(s1.Sum(nil)),
+	}, nil
+}
+
+// integrityFinding builds the finding reported when a tarball hash of the
+// entry is found; the evidence is the hash as written in the blocklist.
+func (e BlocklistEntry) integrityFinding(pkg PackageRef, hash string) Finding {
+	f := e.finding(pkg)
+	f.Type = "integrity"
+	f.Reason = "Matched known-malicious tarball integrity"
+	f.Evidence = hash
+	for _, value := range e.Integrity {
+		if normalizeIntegrity(value) == hash {
+			f.Evidence = value
+			break
+		}
+	}
+	if e.ID == "" {
+		f.RuleID = "integrity/" + hash
+	}
+	if f.Name == "" {
+		f.Name = e.Name
+	}
+	return f
+}
@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeIntegrity(t *testing.T) {
+	payload := []byte("malicious payload")
+	sum1 := sha1.Sum(payload)
+	sum512 := sha512.Sum512(payload)
+	sri1 := "sha1-" + base64.StdEncoding.EncodeToString(sum1[:])
+	sri512 := "sha512-" + base64.StdEncoding.EncodeToString(sum512[:])
+	hex1 := hex.EncodeToString(sum1[:])
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"sha512 SRI", sri512, "sha512:" + hex.EncodeToString(sum512[:])},
+		{"sha1 SRI", sri1, "sha1:" + hex1},
+		{"hex shasum", hex1, "sha1:" + hex1},
+		{"uppercase hex shasum", " " + strings.ToUpper(hex1) + " ", "sha1:" + hex1},
+		{"SRI with options", sri512 + "?foo", "sha512:" + hex.EncodeToString(sum512[:])},
+		{"unknown algorithm", "md5-abc", ""},
+		{"invalid base64", "sha512-!!!", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeIntegrity(tt.value); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBlocklist_MatchIntegrity(t *testing.T) {
+	payload := []byte("malicious payload")
+	sum1 := sha1.Sum(payload)
+	sum512 := sha512.Sum512(payload)
+	sri512 := "sha512-" + base64.StdEncoding.EncodeToString(sum512[:])
+	hex1 := hex.EncodeToString(sum1[:])
+
+	blocklist := newBlocklist([]BlocklistEntry{
+		{Integrity: []string{sri512, hex1}, ID: "MAL-2025-1", Summary: "Credential stealer payload"},
+		{Name: "known-bad", Versions: []string{"1.0.0"}, Integrity: []string{sri512}},
+	})
+
+	tests := []struct {
+		name          string
+		pkg           PackageRef
+		expectedCount int
+	}{
+		{"renamed package with malicious integrity", PackageRef{Name: "fresh-name", Version: "9.9.9", Integrity: sri512}, 2},
+		{"multiple SRI values", PackageRef{Name: "fresh-name", Version: "1.0.0", Integrity: "sha1-AAAA " + sri512}, 2},
+		{"yarn resolved shasum fragment", PackageRef{Name: "other", Version: "1.0.0", Resolved: "https://registry.yarnpkg.com/other/-/other-1.0.0.tgz#" + hex1}, 1},
+		{"name match is not duplicated by integrity", PackageRef{Name: "known-bad", Version: "1.0.0", Integrity: sri512}, 2},
+		{"clean package", PackageRef{Name: "lodash", Version: "4.17.21", Integrity: "sha512-" + base64.StdEncoding.EncodeToString(make([]byte, 64))}, 0},
+		{"package without name", PackageRef{Version: "1.0.0"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := blocklist.Match(tt.pkg)
+			if len(findings) != tt.expectedCount {
+				t.Fatalf("Expected %d findings, got %d: %+v", tt.expectedCount, len(findings), findings)
+			}
+		})
+	}
+
+	findings := blocklist.Match(PackageRef{Name: "fresh-name", Version: "9.9.9", Integrity: sri512})
+	f := findings[0]
+	if f.Type != "integrity" || f.Evidence != sri512 || f.Name != "fresh-name" || f.Advisory != "MAL-2025-1" {
+		t.Errorf("Unexpected integrity finding: %+v", f)
+	}
+}
+
+func TestBlocklist_MatchTarballs(t *testing.T) {
+	dir := t.TempDir()
+	payload := []byte("malicious tarball bytes")
+	os.MkdirAll(filepath.Join(dir, "vendor"), 0755)
+	tarball := filepath.Join(dir, "vendor", "evil-1.0.0.tgz")
+	os.WriteFile(tarball, payload, 0644)
+	os.WriteFile(filepath.Join(dir, "vendor", "clean-1.0.0.tgz"), []byte("clean"), 0644)
+
+	sum := sha1.Sum(payload)
+	blocklist := newBlocklist([]BlocklistEntry{{Name: "evil", Integrity: []string{hex.EncodeToString(sum[:])}}})
+
+	findings, err := blocklist.MatchTarballs(dir)
+	if err != nil {
+		t.Fatalf("MatchTarballs failed: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].File != tarball || findings[0].Type != "integrity" || findings[0].Name != "evil" {
+		t.Errorf("Unexpected finding: %+v", findings[0])
+	}
+
+	// A nested project is a target of its own: its tarballs are reported by
+	// its own call only, while those of installed packages belong to the root.
+	nested := filepath.Join(dir, "packages", "app")
+	os.MkdirAll(filepath.Join(nested, "node_modules", "dep"), 0755)
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{}`), 0644)
+	os.WriteFile(filepath.Join(nested, "package.json"), []byte(`{}`), 0644)
+	os.WriteFile(filepath.Join(nested, "evil-1.0.0.tgz"), payload, 0644)
+	os.WriteFile(filepath.Join(nested, "node_modules", "dep", "package.json"), []byte(`{}`), 0644)
+	os.WriteFile(filepath.Join(nested, "node_modules", "dep", "evil-1.0.0.tgz"), payload, 0644)
+
+	findings, _ = blocklist.MatchTarballs(dir)
+	if len(findings) != 1 || findings[0].File != tarball {
+		t.Errorf("Expected only %s under the root, got %+v", tarball, findings)
+	}
+	findings, _ = blocklist.MatchTarballs(nested)
+	if len(findings) != 2 {
+		t.Errorf("Expected the nested project and its installed package tarballs, got %+v", findings)
+	}
+
+	// Blocklists without hashes skip the walk entirely.
+	findings, err = newBlocklist([]BlocklistEntry{{Name: "evil"}}).MatchTarballs(dir)
+	if err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings, got %+v (err %v)", findings, err)
+	}
+}
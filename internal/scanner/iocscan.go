@@ -1,19 +1,85 @@
 package scanner
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
-// IoCScanner scans files for indicators of compromise.
+// DefaultIncludeGlobs select the files IoC scanning reads by default.
+var DefaultIncludeGlobs = []string{"package.json", "*.js", "*.cjs", "*.mjs", "*.ts", "*.jsx", "*.sh"}
+
+// DefaultMaxFileSize is the size above which files are not scanned, so large
+// minified bundles do not have to be held in memory.
+const DefaultMaxFileSize = 5 << 20
+
+// Default caps on the findings reported per pattern and per file when every
+// match is reported.
+const (
+	DefaultMaxMatchesPerRule = 10
+	DefaultMaxMatchesPerFile = 50
+)
+
+// IoCScanner scans files for indicators of compromise: the declarative Rules
+// and, for callers predating rules, the bare Patterns, each reported as a rule
+// of its own. FileHashes, when set, is checked against the SHA-256 of every
+// scanned file, Obfuscation, when set, scores every scanned file, and
+// Network, when set, extracts the endpoints every scanned file names and
+// attributes them to the package the file belongs to. Unicode, when set,
+// looks for hidden and look-alike characters in every scanned file and in
+// every package.json. Binaries, when set, identifies the executables among
+// all files, scanned or not, and checks them against FileHashes too.
+//
+// Include and Exclude are globs selecting the files to scan. Globs without a
+// slash match file and directory names ("*.js", "test"); others match the
+// path relative to the scanned root, where "**" spans directories
+// ("**/dist/*.min.js"). Files a package.json refers to through "main", "bin"
+// and lifecycle scripts are scanned even if no include glob matches them.
+// Excluded directories are skipped entirely. Files larger than MaxFileSize
+// bytes are skipped; zero disables the limit. With SkipNestedTargets, nested
+// projects that Discover returns as targets of their own are left to their
+// own scan, so each file is attributed to one target.
+//
+// By default each rule is reported once per file, at its first match. With
+// AllMatches every match is reported, up to MaxMatchesPerRule findings per
+// rule and MaxMatchesPerFile findings per file (zero for no cap). Either way
+// the findings count every occurrence of their rule, and a last finding of
+// the file counts the matches of the rules MaxMatchesPerFile left out.
+//
+// Literals hiding a payload, such as atob() and Buffer.from() arguments,
+// String.fromCharCode() codes and escaped strings, are decoded and the rules
+// are applied to the decoded text too, following up to MaxDecodeDepth nested
+// encodings (zero disables decoding). Matches in decoded text are matches of
+// the file: they share the caps above and are numbered with the others.
 type IoCScanner struct {
-	Patterns []*regexp.Regexp
-	MaxDepth int
+	Rules       []Rule
+	Patterns    []*regexp.Regexp
+	MaxDepth    int
+	FileHashes  *FileHashDB
+	Obfuscation *ObfuscationDetector
+	Network     *NetworkDetector
+	Unicode     *UnicodeDetector
+	Binaries    *BinaryDetector
+	Include     []string
+	Exclude     []string
+	MaxFileSize int64
+
+	SkipNestedTargets bool
+
+	MaxDecodeDepth int
+
+	AllMatches        bool
+	MaxMatchesPerRule int
+	MaxMatchesPerFile int
 }
 
-// NewIoCScanner creates a new IoCScanner with the given patterns and max depth.
+// NewIoCScanner creates a new IoCScanner with the given patterns and max
+// depth, scanning the files selected by DefaultIncludeGlobs.
 func NewIoCScanner(patterns []string, maxDepth int) (*IoCScanner, error) {
 	compiled := make([]*regexp.Regexp, 0, len(patterns))
 	for _, pattern := range patterns {
@@ -23,42 +89,123 @@ func NewIoCScanner(patterns []string, maxDepth int) (*IoCScanner, error) {
 		}
 		compiled = append(compiled, re)
 	}
-	return &IoCScanner{Patterns: compiled, MaxDepth: maxDepth}, nil
+	return &IoCScanner{
+		Patterns:    compiled,
+		MaxDepth:    maxDepth,
+		Include:     append([]string(nil), DefaultIncludeGlobs...),
+		MaxFileSize: DefaultMaxFileSize,
+
+		MaxDecodeDepth: DefaultMaxDecodeDepth,
+
+		MaxMatchesPerRule: DefaultMaxMatchesPerRule,
+		MaxMatchesPerFile: DefaultMaxMatchesPerFile,
+	}, nil
+}
+
+// NewIoCScannerFromRules creates a new IoCScanner applying the given rules,
+// with the same defaults as NewIoCScanner.
+func NewIoCScannerFromRules(rules []Rule, maxDepth int) (*IoCScanner, error) {
+	if _, err := compileRules(rules); err != nil {
+		return nil, err
+	}
+	s, err := NewIoCScanner(nil, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	s.Rules = rules
+	return s, nil
 }
 
 // Scan scans the given path for IoCs.
 func (s *IoCScanner) Scan(path string) ([]Finding, error) {
 	findings := []Finding{}
 
-	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+	rules, err := compileRules(s.Rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, re := range s.Patterns {
+		rules = append(rules, compiledRule{
+			Rule: Rule{
+				ID:          "ioc/" + re.String(),
+				Title:       "Matched pattern",
+				Description: "Source matches the IoC pattern " + re.String(),
+				Severity:    SeverityMedium,
+			},
+			compiledCondition: compiledCondition{patterns: []*regexp.Regexp{re}},
+		})
+	}
+
+	include := compileGlobs(s.Include)
+	exclude := compileGlobs(s.Exclude)
+	referenced := map[string]bool{}
+	packages := map[string]PackageRef{}
+
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip paths with errors
 		}
 
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			rel = p
+		}
+		rel = filepath.ToSlash(rel)
+
 		if info.IsDir() {
-			if depth(p) > s.MaxDepth {
+			if depth(p) > s.MaxDepth || (p != path && matchGlobs(exclude, rel)) || s.SkipNestedTargets && nestedTarget(path, p) {
 				return filepath.SkipDir
 			}
+			if pkg, err := parsePackageJSON(filepath.Join(p, "package.json")); err == nil {
+				packages[p] = pkg
+				for _, file := range referencedFiles(pkg) {
+					referenced[file] = true
+				}
+			}
 			return nil
 		}
 
-		if isTargetFile(filepath.Base(p)) {
-			content, err := os.ReadFile(p)
-			if err != nil {
-				return nil
-			}
+		if matchGlobs(exclude, rel) {
+			return nil
+		}
+		pkg := owningPackage(packages, path, p)
 
-			for _, re := range s.Patterns {
-				if match := re.Find(content); match != nil {
-					findings = append(findings, Finding{
-						Type:     "ioc",
-						File:     p,
-						Reason:   "Matched pattern",
-						Evidence: string(match),
-					})
-				}
-			}
+		// Executables are identified whatever their name, and hashed here
+		// since they are not read in full.
+		binaryFindings, sum := s.Binaries.analyze(pkg, p)
+		findings = append(findings, binaryFindings...)
+
+		included := referenced[p] || matchGlobs(include, rel)
+		if !included && sum != "" {
+			findings = append(findings, s.FileHashes.matchSum(p, sum)...)
 		}
+		manifest := s.Unicode != nil && info.Name() == "package.json"
+		if !(included || manifest) {
+			return nil
+		}
+		if s.MaxFileSize > 0 && info.Size() > s.MaxFileSize {
+			return nil
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+
+		src := newRuleSource(content)
+		if !included {
+			// Manifests are only checked for hidden characters
+			findings = append(findings, s.Unicode.analyze(p, src)...)
+			return nil
+		}
+
+		findings = append(findings, s.FileHashes.Match(p, content)...)
+		hits := s.matchRules(rules, rel, src)
+		hits = append(hits, s.matchDecoded(rules, rel, src, nil, nil)...)
+		findings = append(findings, s.limitHits(rules, p, content, hits)...)
+		findings = append(findings, s.Obfuscation.analyze(p, rel, src)...)
+		findings = append(findings, s.Unicode.analyze(p, src)...)
+		findings = append(findings, s.Network.analyze(pkg, p, src, s.MaxDecodeDepth)...)
 		return nil
 	})
 
@@ -69,12 +216,330 @@ func (s *IoCScanner) Scan(path string) ([]Finding, error) {
 	return findings, nil
 }
 
+// ruleHit is a match of the rules of a scan, by index, before the caps on
+// findings apply. Only the offsets are kept until the hit is reported.
+type ruleHit struct {
+	rule    int
+	matches []ruleMatch // One match, or the window of a compound rule
+	content []byte      // The file, or the decoded payload the match is in
+	at      *encodedLiteral
+	steps   []string // Decoding steps from the file to content
+}
+
+// matchRules returns the rule matches in src.
+func (s *IoCScanner) matchRules(rules []compiledRule, rel string, src *ruleSource) []ruleHit {
+	hits := []ruleHit{}
+
+	for i, rule := range rules {
+		if !rule.appliesTo(rel) {
+			continue
+		}
+
+		if rule.compound() {
+			if window := rule.findCompound(src); len(window) > 0 {
+				hits = append(hits, ruleHit{rule: i, matches: window, content: src.content})
+			}
+			continue
+		}
+
+		for _, m := range rule.find(src, true) {
+			hits = append(hits, ruleHit{rule: i, matches: []ruleMatch{m}, content: src.content})
+		}
+	}
+
+	return hits
+}
+
+// limitHits turns the rule matches in file p, in the source and in decoded
+// payloads alike, into findings numbered among the matches of their rule. By
+// default each rule is reported once; with AllMatches every match is, up to
+// MaxMatchesPerRule per rule and MaxMatchesPerFile in all. The rules left out
+// by MaxMatchesPerFile are summed up in a last finding. Compound rules match
+// once per file and are not numbered. Positions and snippets are only built
+// for the matches that are reported.
+func (s *IoCScanner) limitHits(rules []compiledRule, p string, file []byte, hits []ruleHit) []Finding {
+	findings := []Finding{}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].rule < hits[j].rule })
+
+	var starts []int
+	if len(hits) > 0 {
+		starts = lineStarts(file)
+	}
+
+	suppressed, total := []string{}, 0
+	var marker *Finding
+	for i := 0; i < len(hits); {
+		j := i
+		for j < len(hits) && hits[j].rule == hits[i].rule {
+			j++
+		}
+		matches := hits[i:j]
+		i = j
+
+		rule := rules[matches[0].rule]
+		compound := rule.compound()
+		for k, hit := range matches {
+			if k > 0 && (!s.AllMatches || compound) || s.AllMatches && s.MaxMatchesPerRule > 0 && k >= s.MaxMatchesPerRule {
+				break
+			}
+			if s.AllMatches && s.MaxMatchesPerFile > 0 && len(findings) >= s.MaxMatchesPerFile {
+				if marker == nil {
+					marker = &Finding{
+						Type:        "ioc",
+						File:        p,
+						Reason:      "More suspicious patterns matched than are reported per file",
+						Description: fmt.Sprintf("Only the first %d findings of the file are reported", s.MaxMatchesPerFile),
+						RuleID:      "ioc/max-matches-per-file",
+						Remediation: "Review the whole file, or raise the cap on findings per file to list every match",
+					}
+				}
+				left := len(matches) - k
+				if compound {
+					left = 1
+				}
+				total += left
+				if severityRanks[rule.Severity] > severityRanks[marker.Severity] {
+					marker.Severity = rule.Severity
+				}
+				suppressed = append(suppressed, fmt.Sprintf("%s (%d)", rule.ID, left))
+				break
+			}
+
+			f := ruleFinding(rule, p, file, starts, hit)
+			if !compound {
+				f.Count = len(matches)
+				f.Occurrence = k + 1
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	if marker != nil {
+		marker.Evidence = fmt.Sprintf("%d unreported matches: %s", total, strings.Join(suppressed, ", "))
+		findings = append(findings, *marker)
+	}
+	return findings
+}
+
+// ruleFinding builds the finding reported for a match of rule in file p,
+// whose lines start at starts. Matches in decoded payloads are located at the
+// literal they were decoded from.
+func ruleFinding(rule compiledRule, p string, file []byte, starts []int, hit ruleHit) Finding {
+	remediation := rule.Remediation
+	if remediation == "" {
+		remediation = "Review the matched code and remove the package if the behavior is not expected"
+	}
+
+	m, content := hit.matches[0], hit.content
+	start, end := m.start, m.end
+	if hit.at != nil {
+		start, end = hit.at.start, hit.at.end
+	}
+	line := lineOf(starts, start)
+
+	f := Finding{
+		Type:        "ioc",
+		File:        p,
+		Reason:      rule.Title,
+		Description: rule.Description,
+		Evidence:    string(content[m.start:m.end]),
+		Pattern:     m.pattern,
+		Line:        line,
+		Column:      utf8.RuneCount(file[starts[line-1]:start]) + 1,
+		Offset:      start,
+		Snippet:     snippetAt(file, start, end),
+		Decoding:    strings.Join(hit.steps, " > "),
+		Severity:    rule.Severity,
+		RuleID:      rule.ID,
+		References:  rule.References,
+		Remediation: remediation,
+		Source:      rule.Source,
+	}
+	if hit.at != nil {
+		f.Length = end - start
+	}
+
+	if rule.compound() {
+		contentStarts := starts
+		if hit.at != nil {
+			contentStarts = lineStarts(content)
+		}
+		evidence := make([]string, len(hit.matches))
+		patterns := make([]string, len(hit.matches))
+		for j, m := range hit.matches {
+			evidence[j] = fmt.Sprintf("%s (line %d)", content[m.start:m.end], lineOf(contentStarts, m.start))
+			patterns[j] = m.pattern
+		}
+		f.Evidence = strings.Join(evidence, " + ")
+		f.Pattern = strings.Join(patterns, " + ")
+		if hit.at == nil {
+			f.Length = m.end - m.start
+		}
+	}
+	return f
+}
+
 // depth calculates the depth of a path.
 func depth(path string) int {
 	return strings.Count(filepath.Clean(path), string(os.PathSeparator))
 }
 
-// isTargetFile checks if a file is a target for IoC scanning.
-func isTargetFile(name string) bool {
-	return name == "package.json" || name == "index.js" || name == "postinstall.js" || name == "bundle.js"
+// positionAt returns the 1-based line and column of the given byte offset.
+// Columns count Unicode code points.
+func positionAt(content []byte, offset int) (line, column int) {
+	lineStart := bytes.LastIndexByte(content[:offset], '\n') + 1
+	return bytes.Count(content[:offset], []byte("\n")) + 1, utf8.RuneCount(content[lineStart:offset]) + 1
+}
+
+// snippetContext is the number of lines shown before and after a match.
+const snippetContext = 1
+
+// snippetWidth is the number of bytes kept from each line of a snippet, so a
+// match in a minified bundle does not copy the whole bundle into the report.
+const snippetWidth = 160
+
+// snippetAt returns the lines around the match content[start:end], each cut
+// to snippetWidth bytes; the line of the match is cut around the match. The
+// snippet of a match on line n starts on line snippetStart(n).
+func snippetAt(content []byte, start, end int) string {
+	from := bytes.LastIndexByte(content[:start], '\n') + 1
+	for i := 0; i < snippetContext && from > 0; i++ {
+		from = bytes.LastIndexByte(content[:from-1], '\n') + 1
+	}
+	to := start
+	for i := 0; i <= snippetContext && to < len(content); i++ {
+		if next := bytes.IndexByte(content[to:], '\n'); next >= 0 {
+			to += next + 1
+		} else {
+			to = len(content)
+		}
+	}
+
+	lines := []string{}
+	offset := from
+	for _, line := range strings.SplitAfter(string(content[from:to]), "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimRight(line, "\r\n")
+		center := 0
+		if start >= offset && start < offset+len(line) {
+			center = start - offset + min(end-start, snippetWidth)/2
+		}
+		lines = append(lines, cutLine(text, center))
+		offset += len(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// snippetStart returns the first line of the snippet of a match on line.
+func snippetStart(line int) int {
+	return max(1, line-snippetContext)
+}
+
+// cutLine shortens a line to snippetWidth bytes around the byte at center,
+// marking removed text with "...".
+func cutLine(line string, center int) string {
+	if len(line) <= snippetWidth {
+		return line
+	}
+	from := max(0, min(center-snippetWidth/2, len(line)-snippetWidth))
+	to := from + snippetWidth
+	for from > 0 && !utf8.RuneStart(line[from]) {
+		from--
+	}
+	for to < len(line) && !utf8.RuneStart(line[to]) {
+		to++
+	}
+
+	cut := line[from:to]
+	if from > 0 {
+		cut = "..." + cut
+	}
+	if to < len(line) {
+		cut += "..."
+	}
+	return cut
+}
+
+// referencedFiles returns the files the package.json of pkg names as its
+// "main" module, its "bin" commands or the files its lifecycle scripts run.
+func referencedFiles(pkg PackageRef) []string {
+	names := []string{}
+	if pkg.Main != "" {
+		names = append(names, pkg.Main)
+	}
+	for _, command := range sortedKeys(pkg.Bin) {
+		names = append(names, pkg.Bin[command])
+	}
+	for _, script := range lifecycleScripts {
+		names = append(names, scriptFiles(pkg.Scripts[script])...)
+	}
+
+	files := []string{}
+	for _, name := range names {
+		if filepath.IsAbs(name) {
+			continue
+		}
+		files = append(files, filepath.Join(pkg.Path, filepath.FromSlash(name)))
+	}
+	return files
+}
+
+// owningPackage returns the package, among those found under root, holding
+// file p: the one in the closest enclosing directory. Files outside any
+// package belong to a package without a name at root.
+func owningPackage(packages map[string]PackageRef, root, p string) PackageRef {
+	for dir := filepath.Dir(p); ; dir = filepath.Dir(dir) {
+		if pkg, ok := packages[dir]; ok {
+			return pkg
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			return PackageRef{Path: root}
+		}
+	}
+}
+
+// compileGlobs converts globs into regular expressions over slash-separated
+// paths. Globs without a slash match the last path element.
+func compileGlobs(globs []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		glob = strings.TrimPrefix(filepath.ToSlash(glob), "./")
+		var b strings.Builder
+		if strings.Contains(glob, "/") {
+			b.WriteString("^")
+		} else {
+			b.WriteString("(^|/)")
+		}
+		for i := 0; i < len(glob); i++ {
+			switch {
+			case strings.HasPrefix(glob[i:], "**/"):
+				b.WriteString("(.*/)?")
+				i += 2
+			case strings.HasPrefix(glob[i:], "**"):
+				b.WriteString(".*")
+				i++
+			case glob[i] == '*':
+				b.WriteString("[^/]*")
+			case glob[i] == '?':
+				b.WriteString("[^/]")
+			default:
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		}
+		b.WriteString("$")
+		compiled = append(compiled, regexp.MustCompile(b.String()))
+	}
+	return compiled
+}
+
+// matchGlobs reports whether a slash-separated path matches any glob.
+func matchGlobs(globs []*regexp.Regexp, path string) bool {
+	for _, re := range globs {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
 }
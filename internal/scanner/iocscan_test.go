@@ -3,6 +3,7 @@ package scanner
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -186,6 +187,17 @@ func TestIoCScanner_Scan(t *testing.T) {
 	}
 }
 
+func TestNewIoCScanner_DefaultIncludeGlobs(t *testing.T) {
+	scanner, err := NewIoCScanner(nil, 10)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	scanner.Include[0] = "*.txt"
+	if DefaultIncludeGlobs[0] != "package.json" {
+		t.Errorf("Expected the default include globs to be copied, got %v", DefaultIncludeGlobs)
+	}
+}
+
 func TestDepth(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -234,59 +246,246 @@ func TestDepth(t *testing.T) {
 	}
 }
 
-func TestIsTargetFile(t *testing.T) {
+func TestCompileGlobs(t *testing.T) {
 	tests := []struct {
 		name     string
-		filename string
+		glob     string
+		path     string
 		expected bool
 	}{
-		{
-			name:     "package.json",
-			filename: "package.json",
-			expected: true,
-		},
-		{
-			name:     "index.js",
-			filename: "index.js",
-			expected: true,
-		},
-		{
-			name:     "postinstall.js",
-			filename: "postinstall.js",
-			expected: true,
-		},
-		{
-			name:     "bundle.js",
-			filename: "bundle.js",
-			expected: true,
-		},
-		{
-			name:     "other.js",
-			filename: "other.js",
-			expected: false,
-		},
-		{
-			name:     "readme.md",
-			filename: "readme.md",
-			expected: false,
-		},
-		{
-			name:     "package.json.backup",
-			filename: "package.json.backup",
-			expected: false,
-		},
-		{
-			name:     "empty filename",
-			filename: "",
-			expected: false,
-		},
+		{"extension at the root", "*.js", "index.js", true},
+		{"extension in a subdirectory", "*.js", "lib/setup.js", true},
+		{"other extension", "*.js", "index.json", false},
+		{"directory name", "test", "node_modules/pkg/test", true},
+		{"directory name prefix", "test", "node_modules/pkg/tests", false},
+		{"relative path", "dist/*.js", "dist/main.js", true},
+		{"relative path is anchored", "dist/*.js", "lib/dist/main.js", false},
+		{"double star", "**/dist/*.min.js", "node_modules/pkg/dist/app.min.js", true},
+		{"double star matches no directory", "**/dist/*.min.js", "dist/app.min.js", true},
+		{"single star stays in one directory", "lib/*.js", "lib/a/b.js", false},
+		{"question mark", "?.sh", "a.sh", true},
+		{"dots are literal", "*.js", "indexxjs", false},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := isTargetFile(tt.filename)
-			if result != tt.expected {
-				t.Errorf("Expected %v for filename '%s', got %v", tt.expected, tt.filename, result)
+			if got := matchGlobs(compileGlobs([]string{tt.glob}), tt.path); got != tt.expected {
+				t.Errorf("Expected %v for %s against %s, got %v", tt.expected, tt.glob, tt.path, got)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_FileSelection(t *testing.T) {
+	tempDir := t.TempDir()
+	testFiles := map[string]string{
+		"pkg/package.json":                  `{"name": "pkg", "main": "lib/entry", "bin": {"pkg": "cli"}, "scripts": {"postinstall": "node hooks/run.dat"}}`,
+		"pkg/lib/entry":                     `malicious_code`,
+		"pkg/cli":                           `malicious_code`,
+		"pkg/hooks/run.dat":                 `malicious_code`,
+		"pkg/unreferenced":                  `malicious_code`,
+		"pkg/src/app.ts":                    `malicious_code`,
+		"pkg/dist/app.min.js":               `malicious_code`,
+		"pkg/test/fixture.js":               `malicious_code`,
+		"pkg/vendor/huge.js":                `malicious_code` + strings.Repeat(" ", 2048),
+		"pkg/scripts/install.sh":            `malicious_code`,
+		"pkg/types/index.d.ts":              `malicious_code`,
+		"pkg/components/Button.jsx":         `malicious_code`,
+		"pkg/dist/main.cjs":                 `malicious_code`,
+		"pkg/install.mjs":                   `malicious_code`,
+		"pkg/node_modules/dep/dep.js":       `malicious_code`,
+		"pkg/node_modules/dep/package.json": `{"name": "dep", "description": "malicious_code"}`,
+	}
+	for filePath, content := range testFiles {
+		fullPath := filepath.Join(tempDir, filePath)
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte(content), 0644)
+	}
+
+	scanner, err := NewIoCScanner([]string{"malicious_code"}, 20)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	scanner.Exclude = []string{"test", "**/dist/*.min.js", "*.d.ts"}
+	scanner.MaxFileSize = 1024
+
+	findings, err := scanner.Scan(tempDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, f := range findings {
+		rel, _ := filepath.Rel(tempDir, f.File)
+		got[filepath.ToSlash(rel)] = true
+	}
+
+	expected := []string{
+		"pkg/lib/entry",
+		"pkg/cli",
+		"pkg/hooks/run.dat",
+		"pkg/src/app.ts",
+		"pkg/scripts/install.sh",
+		"pkg/components/Button.jsx",
+		"pkg/dist/main.cjs",
+		"pkg/install.mjs",
+		"pkg/node_modules/dep/dep.js",
+		"pkg/node_modules/dep/package.json",
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d scanned files, got %d: %v", len(expected), len(got), got)
+	}
+	for _, file := range expected {
+		if !got[file] {
+			t.Errorf("Expected %s to be scanned", file)
+		}
+	}
+
+	// Manifests are scanned by default but can be excluded
+	scanner.Exclude = append(scanner.Exclude, "package.json")
+	findings, err = scanner.Scan(tempDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	for _, f := range findings {
+		if filepath.Base(f.File) == "package.json" {
+			t.Errorf("Expected excluded package.json not to be scanned, got %+v", f)
+		}
+	}
+	if len(findings) != len(expected)-1 {
+		t.Errorf("Expected %d findings, got %d", len(expected)-1, len(findings))
+	}
+}
+
+func TestPositionAt(t *testing.T) {
+	content := []byte("first\nsecond line\nthird ünïcode match")
+
+	tests := []struct {
+		name           string
+		offset         int
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"start of file", 0, 1, 1},
+		{"middle of first line", 3, 1, 4},
+		{"start of second line", 6, 2, 1},
+		{"after multibyte characters", strings.Index(string(content), "match"), 3, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, column := positionAt(content, tt.offset)
+			if line != tt.expectedLine || column != tt.expectedColumn {
+				t.Errorf("Expected %d:%d, got %d:%d", tt.expectedLine, tt.expectedColumn, line, column)
+			}
+		})
+	}
+}
+
+func TestSnippetAt(t *testing.T) {
+	content := "line1\nline2\nconst cp = require('child_process')\nline4\nline5"
+	start := strings.Index(content, "child_process")
+
+	snippet := snippetAt([]byte(content), start, start+len("child_process"))
+	expected := "line2\nconst cp = require('child_process')\nline4"
+	if snippet != expected {
+		t.Errorf("Expected snippet %q, got %q", expected, snippet)
+	}
+
+	// First line: no preceding context.
+	if snippet := snippetAt([]byte(content), 0, 5); snippet != "line1\nline2" {
+		t.Errorf("Expected snippet at start of file, got %q", snippet)
+	}
+
+	// Minified bundles are cut around the match.
+	minified := strings.Repeat("a=1;", 1000) + "eval(payload)" + strings.Repeat("b=2;", 1000)
+	start = strings.Index(minified, "eval")
+	snippet = snippetAt([]byte(minified), start, start+len("eval(payload)"))
+	if !strings.Contains(snippet, "eval(payload)") {
+		t.Errorf("Expected snippet to contain the match, got %q", snippet)
+	}
+	if !strings.HasPrefix(snippet, "...") || !strings.HasSuffix(snippet, "...") {
+		t.Errorf("Expected cut markers on both sides, got %q", snippet)
+	}
+	if len(snippet) > snippetWidth+6 {
+		t.Errorf("Expected snippet of at most %d bytes, got %d", snippetWidth+6, len(snippet))
+	}
+}
+
+func TestIoCScanner_MatchPosition(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "'use strict'\n\nconst cp = require('child_process')\n"
+	os.WriteFile(filepath.Join(tempDir, "index.js"), []byte(content), 0644)
+
+	scanner, err := NewIoCScanner([]string{"child_process"}, 10)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	findings, err := scanner.Scan(tempDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d", len(findings))
+	}
+
+	f := findings[0]
+	if f.Line != 3 || f.Column != 21 || f.Offset != strings.Index(content, "child_process") {
+		t.Errorf("Expected 3:21 at byte %d, got %d:%d at byte %d", strings.Index(content, "child_process"), f.Line, f.Column, f.Offset)
+	}
+	if f.Snippet != "\nconst cp = require('child_process')" {
+		t.Errorf("Unexpected snippet %q", f.Snippet)
+	}
+}
+
+func TestIoCScanner_AllMatches(t *testing.T) {
+	tempDir := t.TempDir()
+	content := strings.Repeat("require('child_process')\n", 5) + strings.Repeat("eval(x)\n", 3)
+	os.WriteFile(filepath.Join(tempDir, "index.js"), []byte(content), 0644)
+
+	tests := []struct {
+		name           string
+		allMatches     bool
+		maxPerRule     int
+		maxPerFile     int
+		expectedLines  []int
+		expectedCounts []int
+		unreported     string // Evidence of the last finding, when the per-file cap is hit
+	}{
+		{"first match only", false, 0, 0, []int{1, 6}, []int{5, 3}, ""},
+		{"every match", true, 0, 0, []int{1, 2, 3, 4, 5, 6, 7, 8}, []int{5, 5, 5, 5, 5, 3, 3, 3}, ""},
+		{"per-rule cap", true, 2, 0, []int{1, 2, 6, 7}, []int{5, 5, 3, 3}, ""},
+		{"per-file cap", true, 0, 6, []int{1, 2, 3, 4, 5, 6, 0}, []int{5, 5, 5, 5, 5, 3, 0}, `2 unreported matches: ioc/eval\( (2)`},
+		{"per-file cap drops a rule", true, 0, 5, []int{1, 2, 3, 4, 5, 0}, []int{5, 5, 5, 5, 5, 0}, `3 unreported matches: ioc/eval\( (3)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := NewIoCScanner([]string{"child_process", `eval\(`}, 10)
+			if err != nil {
+				t.Fatalf("Failed to create scanner: %v", err)
+			}
+			scanner.AllMatches = tt.allMatches
+			scanner.MaxMatchesPerRule = tt.maxPerRule
+			scanner.MaxMatchesPerFile = tt.maxPerFile
+
+			findings, err := scanner.Scan(tempDir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			if len(findings) != len(tt.expectedLines) {
+				t.Fatalf("Expected %d findings, got %d", len(tt.expectedLines), len(findings))
+			}
+			for i, f := range findings {
+				if f.Line != tt.expectedLines[i] || f.Count != tt.expectedCounts[i] {
+					t.Errorf("Finding %d: expected line %d with count %d, got line %d with count %d", i, tt.expectedLines[i], tt.expectedCounts[i], f.Line, f.Count)
+				}
+			}
+			if last := findings[len(findings)-1]; tt.unreported != "" && (last.RuleID != "ioc/max-matches-per-file" || last.Evidence != tt.unreported || last.Severity != SeverityMedium) {
+				t.Errorf("Expected the unreported matches %q, got %+v", tt.unreported, last)
+			}
+			if tt.allMatches && fingerprint(findings[0].RuleID, findings[0]) == fingerprint(findings[1].RuleID, findings[1]) {
+				t.Error("Expected distinct fingerprints for repeated matches")
 			}
 		})
 	}
@@ -0,0 +1,578 @@
+package scanner
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// jsTokenKind classifies the tokens of JavaScript source.
+type jsTokenKind int
+
+const (
+	jsIdentifier jsTokenKind = iota // Names and keywords
+	jsNumber
+	jsString   // Quoted string, quotes included
+	jsTemplate // Text of a template literal up to a substitution, delimiters included
+	jsRegex
+	jsComment
+	jsPunct   // A single punctuation character
+	jsJSXText // Text between JSX tags
+)
+
+// jsToken is a token spanning src[start:end].
+type jsToken struct {
+	kind       jsTokenKind
+	start, end int
+}
+
+// jsRegexKeywords are the keywords after which a slash starts a regular
+// expression rather than a division.
+var jsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// jsLexer splits JavaScript source into tokens. It is lenient: it never
+// fails, and unterminated literals run to the end of their line or of the
+// source, so obfuscated or invalid code can still be scanned.
+type jsLexer struct {
+	src    []byte
+	pos    int
+	tokens []jsToken
+	noJSX  bool // Set once a JSX element ran to the end of the source
+}
+
+// lexJS returns the tokens of JavaScript (or JSX and TypeScript) source.
+// Whitespace is not returned.
+func lexJS(src []byte) []jsToken {
+	l := &jsLexer{src: src}
+	if bytes.HasPrefix(src, []byte("#!")) {
+		l.pos = l.lineEnd()
+		l.emit(jsComment, 0)
+	}
+	for l.pos < len(l.src) {
+		l.code(false)
+	}
+	return l.tokens
+}
+
+// emit records a token from start to the current position.
+func (l *jsLexer) emit(kind jsTokenKind, start int) {
+	l.tokens = append(l.tokens, jsToken{kind: kind, start: start, end: l.pos})
+}
+
+// code lexes code until the end of the source or, when nested in a template
+// substitution or JSX expression, until the brace closing it.
+func (l *jsLexer) code(nested bool) {
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case c == '/' && l.peek(1) == '/':
+			l.pos = l.lineEnd()
+			l.emit(jsComment, start)
+		case c == '/' && l.peek(1) == '*':
+			if end := bytes.Index(l.src[l.pos+2:], []byte("*/")); end >= 0 {
+				l.pos += 2 + end + 2
+			} else {
+				l.pos = len(l.src)
+			}
+			l.emit(jsComment, start)
+		case c == '/' && l.expressionExpected():
+			l.regex()
+		case c == '\'' || c == '"':
+			l.string(c)
+		case c == '`':
+			l.template()
+		case c == '<' && !l.noJSX && l.expressionExpected() && (isJSIdentStart(l.peekRune(1)) || l.peek(1) == '>'):
+			// A TypeScript type assertion looks the same but is never
+			// closed. Retrying every one would be quadratic.
+			if tokens := len(l.tokens); !l.jsxElement() {
+				l.pos, l.tokens, l.noJSX = start+1, l.tokens[:tokens], true
+				l.emit(jsPunct, start)
+			}
+		case c >= '0' && c <= '9' || c == '.' && l.peek(1) >= '0' && l.peek(1) <= '9':
+			l.number()
+		case isJSIdentStart(l.peekRune(0)):
+			l.identifier()
+		case c == '{':
+			depth++
+			l.pos++
+			l.emit(jsPunct, start)
+		case c == '}':
+			l.pos++
+			l.emit(jsPunct, start)
+			if depth == 0 && nested {
+				return
+			}
+			depth = max(0, depth-1)
+		default:
+			_, size := utf8.DecodeRune(l.src[l.pos:])
+			l.pos += size
+			l.emit(jsPunct, start)
+		}
+	}
+}
+
+// peek returns the byte n bytes ahead, or 0 past the end.
+func (l *jsLexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+// peekRune returns the rune n bytes ahead, or utf8.RuneError past the end.
+func (l *jsLexer) peekRune(n int) rune {
+	if l.pos+n >= len(l.src) {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRune(l.src[l.pos+n:])
+	return r
+}
+
+// lineEnd returns the offset of the end of the current line.
+func (l *jsLexer) lineEnd() int {
+	if i := bytes.IndexByte(l.src[l.pos:], '\n'); i >= 0 {
+		return l.pos + i
+	}
+	return len(l.src)
+}
+
+// expressionExpected reports whether the previous token leaves the lexer
+// where an expression starts, so a slash opens a regular expression and a
+// "<" a JSX element.
+func (l *jsLexer) expressionExpected() bool {
+	for i := len(l.tokens) - 1; i >= 0; i-- {
+		t := l.tokens[i]
+		switch t.kind {
+		case jsComment:
+			continue
+		case jsIdentifier:
+			return jsRegexKeywords[string(l.src[t.start:t.end])]
+		case jsPunct:
+			c := l.src[t.start]
+			return c != ')' && c != ']' && c != '}'
+		case jsTemplate:
+			return l.src[t.end-1] != '`' // Inside a substitution
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// string lexes a quoted string.
+func (l *jsLexer) string(quote byte) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '\\' {
+			l.pos += 2
+			continue
+		}
+		if c == '\n' {
+			break // Unterminated
+		}
+		l.pos++
+		if c == quote {
+			break
+		}
+	}
+	l.pos = min(l.pos, len(l.src))
+	l.emit(jsString, start)
+}
+
+// template lexes a template literal, including the code of its
+// substitutions.
+func (l *jsLexer) template() {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		switch {
+		case l.src[l.pos] == '\\':
+			l.pos += 2
+		case l.src[l.pos] == '`':
+			l.pos++
+			l.emit(jsTemplate, start)
+			return
+		case l.src[l.pos] == '$' && l.peek(1) == '{':
+			l.pos += 2
+			l.emit(jsTemplate, start)
+			l.code(true)
+			if last := l.tokens[len(l.tokens)-1]; last.kind == jsPunct && l.src[last.start] == '}' && last.end == l.pos {
+				start = last.start // The closing brace resumes the literal
+				l.tokens = l.tokens[:len(l.tokens)-1]
+			} else {
+				return // Unterminated substitution
+			}
+		default:
+			l.pos++
+		}
+	}
+	l.pos = min(l.pos, len(l.src))
+	l.emit(jsTemplate, start)
+}
+
+// regex lexes a regular expression literal and its flags.
+func (l *jsLexer) regex() {
+	start := l.pos
+	l.pos++
+	class := false
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		c := l.src[l.pos]
+		l.pos++
+		if c == '\\' {
+			l.pos++
+		} else if c == '[' {
+			class = true
+		} else if c == ']' {
+			class = false
+		} else if c == '/' && !class {
+			break
+		}
+	}
+	l.pos = min(l.pos, len(l.src))
+	for l.pos < len(l.src) && isJSIdentPart(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	l.emit(jsRegex, start)
+}
+
+// number lexes a numeric literal.
+func (l *jsLexer) number() {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') && !strings.HasPrefix(strings.ToLower(string(l.src[start:l.pos])), "0x") {
+			l.pos++
+			continue
+		}
+		if c != '.' && c != '_' && !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') {
+			break
+		}
+		l.pos++
+	}
+	l.emit(jsNumber, start)
+}
+
+// identifier lexes a name or keyword.
+func (l *jsLexer) identifier() {
+	start := l.pos
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRune(l.src[l.pos:])
+		if !isJSIdentPart(r) {
+			break
+		}
+		l.pos += size
+	}
+	l.emit(jsIdentifier, start)
+}
+
+// jsxElement lexes a JSX element or fragment with its children and reports
+// whether it was closed.
+func (l *jsLexer) jsxElement() bool {
+	closing, selfClosing, ok := l.jsxTag()
+	if !ok || closing || selfClosing {
+		return ok
+	}
+	for l.pos < len(l.src) {
+		start := l.pos
+		switch l.src[l.pos] {
+		case '<':
+			if l.peek(1) == '/' {
+				_, _, ok := l.jsxTag()
+				return ok
+			}
+			if !l.jsxElement() {
+				return false
+			}
+		case '{':
+			l.pos++
+			l.emit(jsPunct, start)
+			l.code(true)
+		default:
+			for l.pos < len(l.src) && l.src[l.pos] != '<' && l.src[l.pos] != '{' {
+				l.pos++
+			}
+			if len(bytes.TrimSpace(l.src[start:l.pos])) > 0 {
+				l.emit(jsJSXText, start)
+			}
+		}
+	}
+	return false
+}
+
+// jsxTag lexes a JSX opening or closing tag and reports which it was,
+// whether it closes itself and whether it ended.
+func (l *jsLexer) jsxTag() (closing, selfClosing, ok bool) {
+	start := l.pos
+	l.pos++
+	l.emit(jsPunct, start)
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		start := l.pos
+		switch {
+		case c == '>':
+			l.pos++
+			l.emit(jsPunct, start)
+			return closing, selfClosing, true
+		case c == '/':
+			if l.tokens[len(l.tokens)-1].end == start && l.src[start-1] == '<' {
+				closing = true
+			} else {
+				selfClosing = true
+			}
+			l.pos++
+			l.emit(jsPunct, start)
+		case c == '"' || c == '\'':
+			// JSX attribute strings have no escapes.
+			l.pos++
+			for l.pos < len(l.src) && l.src[l.pos] != c {
+				l.pos++
+			}
+			l.pos = min(l.pos+1, len(l.src))
+			l.emit(jsString, start)
+		case c == '{':
+			l.pos++
+			l.emit(jsPunct, start)
+			l.code(true)
+		case isJSIdentStart(l.peekRune(0)):
+			for l.pos < len(l.src) {
+				r, size := utf8.DecodeRune(l.src[l.pos:])
+				if !isJSIdentPart(r) && r != '-' {
+					break
+				}
+				l.pos += size
+			}
+			l.emit(jsIdentifier, start)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.pos++
+		default:
+			l.pos++
+			l.emit(jsPunct, start)
+		}
+	}
+	return closing, selfClosing, false
+}
+
+// isJSIdentStart reports whether r can start an identifier.
+func isJSIdentStart(r rune) bool {
+	return r == '$' || r == '_' || r == '\\' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= utf8.RuneSelf && r != utf8.RuneError && unicode.IsLetter(r)
+}
+
+// isJSIdentPart reports whether r can continue an identifier. Zero-width
+// joiners are allowed, as in JavaScript.
+func isJSIdentPart(r rune) bool {
+	return isJSIdentStart(r) || r >= '0' && r <= '9' || r == '‌' || r == '‍' ||
+		r >= utf8.RuneSelf && (unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || unicode.Is(unicode.Pc, r))
+}
+
+// Scopes a rule pattern can be restricted to.
+const (
+	ScopeAll         = "all"
+	ScopeCode        = "code"
+	ScopeStrings     = "strings"
+	ScopeIdentifiers = "identifiers"
+)
+
+// maskJS returns a copy of src in which every byte outside the given scope
+// is blanked with a space. Line breaks are kept, so offsets, lines and
+// columns in the copy are those of src.
+//
+// The code scope drops comments and the text of string, template and JSX
+// literals. The strings scope keeps only that text, without delimiters. The
+// identifiers scope keeps only names and keywords.
+func maskJS(src []byte, tokens []jsToken, scope string) []byte {
+	masked := make([]byte, len(src))
+	for i, c := range src {
+		if c == '\n' || c == '\r' {
+			masked[i] = c
+		} else {
+			masked[i] = ' '
+		}
+	}
+
+	keep := func(start, end int) {
+		for i := start; i < end; i++ {
+			if src[i] != '\n' && src[i] != '\r' {
+				masked[i] = src[i]
+			}
+		}
+	}
+	for _, t := range tokens {
+		switch scope {
+		case ScopeCode:
+			if t.kind != jsComment && t.kind != jsString && t.kind != jsTemplate && t.kind != jsJSXText {
+				keep(t.start, t.end)
+			}
+		case ScopeStrings:
+			switch t.kind {
+			case jsString, jsTemplate:
+				start, end := literalText(src, t)
+				keep(start, end)
+			case jsJSXText:
+				keep(t.start, t.end)
+			}
+		case ScopeIdentifiers:
+			if t.kind == jsIdentifier {
+				keep(t.start, t.end)
+			}
+		}
+	}
+	return masked
+}
+
+// literalText returns the span of the text of a string or template token,
+// without its delimiters.
+func literalText(src []byte, t jsToken) (start, end int) {
+	start, end = t.start+1, t.end
+	if t.kind == jsTemplate && strings.HasSuffix(string(src[t.start:t.end]), "${") {
+		end -= 2
+	} else if end-start >= 1 && (src[end-1] == src[t.start] || t.kind == jsTemplate && src[end-1] == '`') {
+		end-- // Terminated
+	}
+	return start, max(start, end)
+}
+
+// jsModuleRef is a module required or imported by JavaScript source.
+type jsModuleRef struct {
+	name       string
+	start, end int // The require call or import statement
+}
+
+// jsModules returns the modules loaded with require() or import() calls
+// and import and export statements. Module names built by concatenating
+// literals ("child_" + "process") or written with escapes are resolved, and
+// the "node:" prefix of built-in modules is dropped.
+func jsModules(src []byte, tokens []jsToken) []jsModuleRef {
+	code := make([]jsToken, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind != jsComment {
+			code = append(code, t)
+		}
+	}
+	text := func(t jsToken) string { return string(src[t.start:t.end]) }
+	punct := func(i int, c string) bool {
+		return i >= 0 && i < len(code) && code[i].kind == jsPunct && text(code[i]) == c
+	}
+
+	refs := []jsModuleRef{}
+	for i, t := range code {
+		if t.kind != jsIdentifier {
+			continue
+		}
+		switch name := text(t); {
+		case (name == "require" || name == "import" && !punct(i-1, ".")) && punct(i+1, "("):
+			if value, next, ok := jsConcatenation(src, code, i+2); ok && punct(next, ")") {
+				refs = append(refs, jsModuleRef{name: moduleName(value), start: t.start, end: code[next].end})
+			}
+		case name == "from" || name == "import" && !punct(i-1, "."):
+			if value, next, ok := jsConcatenation(src, code, i+1); ok && next == i+2 {
+				refs = append(refs, jsModuleRef{name: moduleName(value), start: t.start, end: code[i+1].end})
+			}
+		}
+	}
+	return refs
+}
+
+// jsConcatenation evaluates the literals joined by "+" starting at
+// code[i] and returns their value and the index of the token after them.
+func jsConcatenation(src []byte, code []jsToken, i int) (string, int, bool) {
+	var b strings.Builder
+	for ok := false; i < len(code); i++ {
+		value, isLiteral := jsLiteralValue(src, code[i])
+		if !isLiteral {
+			return b.String(), i, ok
+		}
+		b.WriteString(value)
+		ok = true
+		if i+1 >= len(code) || code[i+1].kind != jsPunct || src[code[i+1].start] != '+' {
+			return b.String(), i + 1, true
+		}
+		i++
+	}
+	return "", i, false
+}
+
+// jsLiteralValue returns the value of a string literal or of a template
+// literal without substitutions.
+func jsLiteralValue(src []byte, t jsToken) (string, bool) {
+	raw := string(src[t.start:t.end])
+	if t.kind != jsString && !(t.kind == jsTemplate && len(raw) >= 2 && raw[0] == '`' && raw[len(raw)-1] == '`') {
+		return "", false
+	}
+	start, end := literalText(src, t)
+	return unescapeJS(string(src[start:end])), true
+}
+
+// unescapeJS resolves the escape sequences of a JavaScript string literal.
+// Malformed escapes are kept as written.
+func unescapeJS(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case '\n':
+			// Line continuation
+		case 'x', 'u':
+			digits := 2
+			if c == 'u' {
+				digits = 4
+			}
+			hex := ""
+			if c == 'u' && i+1 < len(s) && s[i+1] == '{' {
+				if end := strings.IndexByte(s[i:], '}'); end > 0 {
+					hex = s[i+2 : i+end]
+					digits = end
+				}
+			} else if i+digits < len(s) {
+				hex = s[i+1 : i+1+digits]
+			}
+			if r, err := strconv.ParseUint(hex, 16, 32); err == nil && hex != "" {
+				b.WriteRune(rune(r))
+				i += digits
+			} else {
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// moduleName drops the "node:" prefix of built-in modules.
+func moduleName(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), "node:")
+}
@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexJS(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string // kind:text of each token
+	}{
+		{
+			name:     "comments and strings",
+			src:      "// eval(x)\nconst a = 'it\\'s' /* b */ + \"c\"",
+			expected: []string{"comment:// eval(x)", "ident:const", "ident:a", "punct:=", `string:'it\'s'`, "comment:/* b */", "punct:+", `string:"c"`},
+		},
+		{
+			name:     "regex and division",
+			src:      "x = a / b / c; y = /[/]eval\\//g.test(s); return /x/",
+			expected: []string{"ident:x", "punct:=", "ident:a", "punct:/", "ident:b", "punct:/", "ident:c", "punct:;", "ident:y", "punct:=", `regex:/[/]eval\//g`, "punct:.", "ident:test", "punct:(", "ident:s", "punct:)", "punct:;", "ident:return", "regex:/x/"},
+		},
+		{
+			name:     "template with substitutions",
+			src:      "`a ${ {b: `c${d}`}.b } e`",
+			expected: []string{"template:`a ${", "punct:{", "ident:b", "punct::", "template:`c${", "ident:d", "template:}`", "punct:}", "punct:.", "ident:b", "template:} e`"},
+		},
+		{
+			name:     "jsx",
+			src:      "return <div className=\"x\" onClick={() => run('y')}>Hello {name}<br/></div>",
+			expected: []string{"ident:return", "punct:<", "ident:div", "ident:className", "punct:=", `string:"x"`, "ident:onClick", "punct:=", "punct:{", "punct:(", "punct:)", "punct:=", "punct:>", "ident:run", "punct:(", "string:'y'", "punct:)", "punct:}", "punct:>", "jsx:Hello ", "punct:{", "ident:name", "punct:}", "punct:<", "ident:br", "punct:/", "punct:>", "punct:<", "punct:/", "ident:div", "punct:>"},
+		},
+		{
+			name:     "comparison and unclosed type assertion",
+			src:      "if (a < b) x = <T>y",
+			expected: []string{"ident:if", "punct:(", "ident:a", "punct:<", "ident:b", "punct:)", "ident:x", "punct:=", "punct:<", "ident:T", "punct:>", "ident:y"},
+		},
+		{
+			name:     "unterminated literals",
+			src:      "a = 'open\nb = `tail",
+			expected: []string{"ident:a", "punct:=", "string:'open", "ident:b", "punct:=", "template:`tail"},
+		},
+	}
+
+	names := map[jsTokenKind]string{
+		jsIdentifier: "ident", jsNumber: "number", jsString: "string", jsTemplate: "template",
+		jsRegex: "regex", jsComment: "comment", jsPunct: "punct", jsJSXText: "jsx",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, token := range lexJS([]byte(tt.src)) {
+				got = append(got, names[token.kind]+":"+tt.src[token.start:token.end])
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected tokens\n%q\ngot\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMaskJS(t *testing.T) {
+	src := "// require('child_process')\nconst msg = 'run eval(x)' + `id ${user}`\neval(msg)"
+	tests := []struct {
+		scope    string
+		expected string // The words left visible
+		kept     string // A word expected at its original offset
+	}{
+		{ScopeCode, "const msg = + user eval(msg)", "eval(msg)"},
+		{ScopeStrings, "run eval(x) id", "run eval(x)"},
+		{ScopeIdentifiers, "const msg user eval msg", "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			masked := string(maskJS([]byte(src), lexJS([]byte(src)), tt.scope))
+			if len(masked) != len(src) || strings.Count(masked, "\n") != strings.Count(src, "\n") {
+				t.Fatalf("Expected masking to keep offsets and lines, got %q", masked)
+			}
+			if got := strings.Join(strings.Fields(masked), " "); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if strings.Index(masked, tt.kept) != strings.LastIndex(src, tt.kept) {
+				t.Errorf("Expected %q at offset %d, got %d", tt.kept, strings.LastIndex(src, tt.kept), strings.Index(masked, tt.kept))
+			}
+		})
+	}
+}
+
+func TestJSModules(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{"require", `const cp = require('child_process')`, []string{"child_process"}},
+		{"whitespace", "const cp = require (\n  \"child_process\"\n)", []string{"child_process"}},
+		{"concatenation", `const cp = require('child_' + "pro" + ` + "`cess`" + `)`, []string{"child_process"}},
+		{"escapes", `require('\x63hild_process'); require("\u{63}hild_process")`, []string{"child_process", "child_process"}},
+		{"node prefix", `require('node:child_process')`, []string{"child_process"}},
+		{"member require", `module.require('fs'); process.mainModule.require('os')`, []string{"fs", "os"}},
+		{"imports", "import cp from 'child_process'\nimport 'http'\nexport { x } from \"./x\"\nconst m = await import('node:os')", []string{"child_process", "http", "./x", "os"}},
+		{"comments and mentions", "// require('child_process')\nconst s = \"require('child_process')\"", []string{}},
+		{"computed names", `require(name); require('child_' + suffix); require(` + "`${a}`" + `)`, []string{}},
+		{"method named import", `obj.import('x')`, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			got := []string{}
+			for _, ref := range jsModules(src, lexJS(src)) {
+				got = append(got, ref.name)
+				if !strings.Contains(string(src[ref.start:ref.end]), "require") && !strings.Contains(string(src[ref.start:ref.end]), "import") && !strings.HasPrefix(string(src[ref.start:ref.end]), "from") {
+					t.Errorf("Expected the span to cover the call, got %q", src[ref.start:ref.end])
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected modules %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
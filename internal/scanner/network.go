@@ -0,0 +1,742 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of network endpoints.
+const (
+	EndpointURL  = "url"
+	EndpointIP   = "ip"
+	EndpointHost = "host"
+)
+
+// urlPattern matches an absolute URL of a network protocol.
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?|wss?|ftp)://[^\s'"` + "`" + `<>\\{}|^\[\]]*(?:\[[0-9a-f:.]+\][^\s'"` + "`" + `<>\\{}|^\[\]]*)?`)
+
+// ipv4Pattern matches a dotted IPv4 address.
+var ipv4Pattern = regexp.MustCompile(`(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(?:\.(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}`)
+
+// ipv6Pattern matches text that may be an IPv6 address; candidates are
+// checked with net.ParseIP.
+var ipv6Pattern = regexp.MustCompile(`(?i)[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}`)
+
+// hostPattern matches a lowercase DNS name with a port, if any.
+var hostPattern = regexp.MustCompile(`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+([a-z]{2,63})(?::\d{1,5})?$`)
+
+// hostTLDs are the top-level domains a bare name must end in to count as a
+// host, so property paths and file names ("process.env", "index.js") do not.
+// Names within the domain of an ExfilService or a network IoC count whatever
+// their TLD.
+var hostTLDs = map[string]bool{
+	"com": true, "net": true, "org": true, "io": true, "dev": true, "app": true, "co": true, "ai": true,
+	"me": true, "info": true, "biz": true, "xyz": true, "top": true, "site": true, "online": true, "club": true,
+	"shop": true, "store": true, "tech": true, "cloud": true, "live": true, "pro": true, "link": true, "click": true,
+	"win": true, "bid": true, "icu": true, "buzz": true, "fun": true, "space": true, "website": true, "host": true,
+	"ru": true, "cn": true, "su": true, "ir": true, "kp": true, "tk": true, "ml": true, "ga": true,
+	"cf": true, "gq": true, "pw": true, "cc": true, "ws": true, "tv": true, "us": true, "uk": true,
+	"de": true, "fr": true, "jp": true, "in": true, "br": true, "nl": true, "eu": true, "ca": true,
+	"au": true, "es": true, "it": true, "pl": true, "ch": true, "se": true, "no": true, "kr": true,
+	"vn": true, "id": true, "tr": true, "ua": true, "gov": true, "edu": true,
+}
+
+// fileExtensionTLDs are the top-level domains that are also common file
+// extensions ("lcov.info", "build.pl", "tar.br"); names with just one label
+// before them are files rather than hosts.
+var fileExtensionTLDs = map[string]bool{"info": true, "pl": true, "cc": true, "ml": true, "in": true, "app": true, "br": true}
+
+// manifestContactFields are the package.json fields naming the people behind
+// a package and where to report bugs, whose addresses the code never contacts.
+var manifestContactFields = map[string]bool{"author": true, "contributors": true, "maintainers": true, "bugs": true}
+
+// reservedDomains are the names reserved for documentation and local use;
+// endpoints within them are never contacted.
+var reservedDomains = []string{"example.com", "example.net", "example.org", "localhost", "test", "invalid", "local", "example"}
+
+// reservedNetworks are the loopback, unspecified, documentation, link-local
+// and multicast ranges; addresses within them are not remote endpoints.
+var reservedNetworks = parseNetworks(
+	"0.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "255.255.255.255/32",
+	"::/128", "::1/128", "2001:db8::/32", "fe80::/10", "ff00::/8",
+)
+
+// oidPrefixes start the ASN.1 object identifiers of X.509 and cryptographic
+// code, which look like IPv4 addresses ("2.5.29.17").
+var oidPrefixes = []string{"1.2.", "1.3.", "2.5.", "2.16.", "2.23."}
+
+// ExfilService is a service commonly abused to receive stolen data: anyone
+// can create an endpoint on it that collects whatever is sent there. URLs on
+// Domain or its subdomains whose path starts with Path are endpoints of the
+// service.
+type ExfilService struct {
+	ID     string
+	Name   string
+	Domain string
+	Path   string
+}
+
+// ExfilServices are the webhook, request-capture, tunnel and paste services
+// malicious packages send data to.
+var ExfilServices = []ExfilService{
+	{"discord-webhook", "Discord webhook", "discord.com", "/api/webhooks"},
+	{"discord-webhook", "Discord webhook", "discordapp.com", "/api/webhooks"},
+	{"telegram-bot", "Telegram bot API", "api.telegram.org", ""},
+	{"webhook-site", "webhook.site", "webhook.site", ""},
+	{"pipedream", "Pipedream request bin", "pipedream.net", ""},
+	{"requestbin", "RequestBin", "requestbin.net", ""},
+	{"requestbin", "RequestBin", "requestbin.com", ""},
+	{"beeceptor", "Beeceptor", "beeceptor.com", ""},
+	{"interactsh", "Interactsh", "interact.sh", ""},
+	{"interactsh", "Interactsh", "oast.fun", ""},
+	{"interactsh", "Interactsh", "oast.live", ""},
+	{"interactsh", "Interactsh", "oast.me", ""},
+	{"interactsh", "Interactsh", "oast.online", ""},
+	{"interactsh", "Interactsh", "oast.pro", ""},
+	{"interactsh", "Interactsh", "oast.site", ""},
+	{"burp-collaborator", "Burp Collaborator", "burpcollaborator.net", ""},
+	{"burp-collaborator", "Burp Collaborator", "oastify.com", ""},
+	{"ngrok", "ngrok tunnel", "ngrok.io", ""},
+	{"ngrok", "ngrok tunnel", "ngrok.app", ""},
+	{"ngrok", "ngrok tunnel", "ngrok-free.app", ""},
+	{"cloudflare-tunnel", "Cloudflare quick tunnel", "trycloudflare.com", ""},
+	{"pastebin", "Pastebin", "pastebin.com", ""},
+	{"paste-ee", "paste.ee", "paste.ee", ""},
+	{"hastebin", "Hastebin", "hastebin.com", ""},
+	{"transfer-sh", "transfer.sh", "transfer.sh", ""},
+}
+
+// NetworkIoC is a known-malicious network indicator: a domain, which also
+// matches its subdomains, an IP address, a CIDR range or a URL prefix.
+type NetworkIoC struct {
+	Indicator  string   `json:"indicator"`
+	Campaign   string   `json:"campaign"`
+	Severity   string   `json:"severity,omitempty"`
+	References []string `json:"references,omitempty"`
+
+	// Source is the file the indicator was loaded from.
+	Source string `json:"-"`
+}
+
+// NetworkIoCDB is a collection of network IoCs indexed by domain, address,
+// range and URL prefix.
+type NetworkIoCDB struct {
+	Entries []NetworkIoC
+
+	domains  map[string]int
+	networks []indexedNetwork
+	urls     []int
+}
+
+// indexedNetwork is the range of addresses an IP or CIDR entry matches.
+type indexedNetwork struct {
+	network *net.IPNet
+	entry   int
+}
+
+// newNetworkIoCDB creates an indexed database from entries.
+func newNetworkIoCDB(entries []NetworkIoC) *NetworkIoCDB {
+	db := &NetworkIoCDB{Entries: entries}
+	db.buildIndex()
+	return db
+}
+
+// buildIndex sorts the entries by kind of indicator; later entries for the
+// same domain replace earlier ones.
+func (db *NetworkIoCDB) buildIndex() {
+	db.domains = map[string]int{}
+	db.networks = nil
+	db.urls = nil
+	for i, entry := range db.Entries {
+		indicator := strings.ToLower(entry.Indicator)
+		if strings.Contains(indicator, "://") {
+			db.urls = append(db.urls, i)
+		} else if network := parseNetwork(indicator); network != nil {
+			db.networks = append(db.networks, indexedNetwork{network, i})
+		} else {
+			db.domains[indicator] = i
+		}
+	}
+}
+
+// parseNetworks parses CIDR ranges known to be valid.
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		networks[i] = parseNetwork(cidr)
+	}
+	return networks
+}
+
+// parseNetwork parses an IP address or CIDR range, or returns nil.
+func parseNetwork(s string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// LoadNetworkIoCs loads network IoCs from a JSON array of entries or from a
+// CSV file with "indicator,campaign[,severity]" rows. The format is chosen by
+// the file extension; a CSV header row is skipped. Domains may be written as
+// "*.example.com".
+func LoadNetworkIoCs(path string) (*NetworkIoCDB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []NetworkIoC
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = readNetworkIoCCSV(file)
+	} else {
+		err = json.NewDecoder(file).Decode(&entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		indicator := strings.ToLower(strings.TrimSpace(entries[i].Indicator))
+		indicator = strings.TrimSuffix(strings.TrimPrefix(indicator, "*."), ".")
+		valid := parseNetwork(indicator) != nil || hostPattern.MatchString(indicator) && !strings.Contains(indicator, ":")
+		if strings.Contains(indicator, "://") {
+			u, err := url.Parse(indicator)
+			valid = err == nil && u.Host != ""
+		}
+		if !valid {
+			return nil, fmt.Errorf("entry %d: invalid indicator %q", i+1, entries[i].Indicator)
+		}
+		entries[i].Indicator = indicator
+		entries[i].Source = path
+	}
+	return newNetworkIoCDB(entries), nil
+}
+
+// readNetworkIoCCSV decodes the rows of a CSV indicator list.
+func readNetworkIoCCSV(r io.Reader) ([]NetworkIoC, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []NetworkIoC{}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "indicator") {
+			continue // Header row
+		}
+		entry := NetworkIoC{Indicator: record[0]}
+		if len(record) > 1 {
+			entry.Campaign = record[1]
+		}
+		if len(record) > 2 {
+			entry.Severity = record[2]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Lookup returns the IoC matching an endpoint of the given kind: the URL
+// prefixes for URLs, then the domain of the host or its parent domains, or
+// the ranges of an address.
+func (db *NetworkIoCDB) Lookup(kind, value string) (NetworkIoC, bool) {
+	if db == nil {
+		return NetworkIoC{}, false
+	}
+	if db.domains == nil {
+		db.buildIndex()
+	}
+
+	value = strings.ToLower(value)
+	host := value
+	if kind == EndpointURL {
+		for _, i := range db.urls {
+			if strings.HasPrefix(value, db.Entries[i].Indicator) {
+				return db.Entries[i], true
+			}
+		}
+		host = endpointHost(value)
+	}
+
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		for _, n := range db.networks {
+			if n.network.Contains(ip) {
+				return db.Entries[n.entry], true
+			}
+		}
+		return NetworkIoC{}, false
+	}
+	for name := host; name != ""; {
+		if i, ok := db.domains[name]; ok {
+			return db.Entries[i], true
+		}
+		dot := strings.IndexByte(name, '.')
+		if dot < 0 {
+			break
+		}
+		name = name[dot+1:]
+	}
+	return NetworkIoC{}, false
+}
+
+// Endpoint is a network endpoint named in a package's files.
+type Endpoint struct {
+	Kind     string `json:"kind"` // EndpointURL, EndpointIP or EndpointHost
+	Value    string `json:"value"`
+	Host     string `json:"host"`
+	File     string `json:"file"` // Where it is first named
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Decoding string `json:"decoding,omitempty"` // Encodings hiding it, as for Finding
+	Count    int    `json:"count"`              // Times it is named in the package
+	Service  string `json:"service,omitempty"`  // Name of the ExfilService it belongs to
+	IoC      bool   `json:"ioc,omitempty"`      // Whether it matches a network IoC
+}
+
+// PackageEndpoints lists the endpoints named in the files of a package, or of
+// a scanned directory outside any package when Name is empty.
+type PackageEndpoints struct {
+	Name      string     `json:"name"`
+	Version   string     `json:"version"`
+	Path      string     `json:"path"`
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// NetworkDetector extracts the URLs, IP addresses and host names from the
+// string literals of scanned files, including decoded payloads, and from
+// shell scripts as a whole. Endpoints matching IoCs or belonging to an
+// ExfilService are reported; all of them are kept, per package, for
+// Endpoints.
+type NetworkDetector struct {
+	IoCs *NetworkIoCDB
+
+	packages map[string]*PackageEndpoints
+	seen     map[string]map[string]int // Index of each endpoint in its package
+}
+
+// NewNetworkDetector creates a NetworkDetector without IoCs.
+func NewNetworkDetector() *NetworkDetector {
+	return &NetworkDetector{}
+}
+
+// networkEndpoint is an endpoint located in a scanned file.
+type networkEndpoint struct {
+	kind, value, host string
+	start, end        int
+	decoding          []string
+}
+
+// Analyze extracts the endpoints named in the file at path, which belongs to
+// pkg, and reports the suspicious ones.
+func (d *NetworkDetector) Analyze(pkg PackageRef, path string, content []byte) []Finding {
+	return d.analyze(pkg, path, newRuleSource(content), DefaultMaxDecodeDepth)
+}
+
+// analyze records the endpoints of a scanned file, following up to
+// maxDecodeDepth nested encodings; a nil detector reports nothing.
+func (d *NetworkDetector) analyze(pkg PackageRef, path string, src *ruleSource, maxDecodeDepth int) []Finding {
+	findings := []Finding{}
+	if d == nil {
+		return findings
+	}
+
+	shell := strings.HasSuffix(path, ".sh")
+	var contacts [][2]int
+	if filepath.Base(path) == "package.json" {
+		contacts = manifestFieldSpans(src.content, manifestContactFields)
+	}
+	reported := map[string]bool{}
+	for _, e := range fileEndpoints(src, shell, maxDecodeDepth, d.knownDomain) {
+		if inSpans(contacts, e.start) {
+			continue
+		}
+		line, column := positionAt(src.content, e.start)
+		endpoint := Endpoint{
+			Kind:     e.kind,
+			Value:    e.value,
+			Host:     e.host,
+			File:     path,
+			Line:     line,
+			Column:   column,
+			Decoding: strings.Join(e.decoding, " > "),
+			Count:    1,
+		}
+		service, isService := exfilService(e.kind, e.value, e.host)
+		ioc, isIoC := d.IoCs.Lookup(e.kind, e.value)
+		endpoint.Service = service.Name
+		endpoint.IoC = isIoC
+		d.record(pkg, endpoint)
+
+		if !(isService || isIoC) || reported[e.value] {
+			continue
+		}
+		reported[e.value] = true
+
+		f := Finding{
+			Type:        "network",
+			Name:        pkg.Name,
+			Version:     pkg.Version,
+			Path:        pkg.Path,
+			File:        path,
+			Reason:      "Contacts " + service.Name + ", a service used to exfiltrate data",
+			Evidence:    e.value,
+			Line:        line,
+			Column:      column,
+			Offset:      e.start,
+			Length:      e.end - e.start,
+			Snippet:     snippetAt(src.content, e.start, e.end),
+			Decoding:    endpoint.Decoding,
+			Severity:    SeverityMedium,
+			RuleID:      "network/" + service.ID,
+			Remediation: "Check what the package sends to this endpoint and remove the package unless the traffic is expected",
+		}
+		if isIoC {
+			f.Reason = "Contacts a known-malicious endpoint"
+			if ioc.Campaign != "" {
+				f.Reason += " (" + ioc.Campaign + ")"
+			}
+			f.Campaign = ioc.Campaign
+			f.Severity = normalizeSeverity(ioc.Severity, SeverityCritical)
+			f.RuleID = "network/" + ioc.Indicator
+			f.References = ioc.References
+			f.Source = ioc.Source
+			f.Remediation = "Remove the package, block the endpoint and rotate any credentials available to the install"
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// knownDomain reports whether host is within the domain of an ExfilService
+// or of a network IoC. Such names are hosts whatever their TLD.
+func (d *NetworkDetector) knownDomain(host string) bool {
+	if _, ok := exfilService(EndpointHost, host, host); ok {
+		return true
+	}
+	_, ok := d.IoCs.Lookup(EndpointHost, host)
+	return ok
+}
+
+// record adds an endpoint to the inventory of pkg, counting repeats.
+func (d *NetworkDetector) record(pkg PackageRef, endpoint Endpoint) {
+	if d.packages == nil {
+		d.packages = map[string]*PackageEndpoints{}
+		d.seen = map[string]map[string]int{}
+	}
+	entry, ok := d.packages[pkg.Path]
+	if !ok {
+		entry = &PackageEndpoints{Name: pkg.Name, Version: pkg.Version, Path: pkg.Path, Endpoints: []Endpoint{}}
+		d.packages[pkg.Path] = entry
+		d.seen[pkg.Path] = map[string]int{}
+	}
+	if i, ok := d.seen[pkg.Path][endpoint.Value]; ok {
+		entry.Endpoints[i].Count++
+		return
+	}
+	d.seen[pkg.Path][endpoint.Value] = len(entry.Endpoints)
+	entry.Endpoints = append(entry.Endpoints, endpoint)
+}
+
+// Endpoints returns the endpoints recorded so far, per package, sorted by
+// package path.
+func (d *NetworkDetector) Endpoints() []PackageEndpoints {
+	packages := []PackageEndpoints{}
+	if d == nil {
+		return packages
+	}
+	for _, entry := range d.packages {
+		packages = append(packages, *entry)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Path < packages[j].Path })
+	return packages
+}
+
+// fileEndpoints returns the endpoints in the string literals of src, or in
+// all of it for shell scripts and decoded text, and in the payloads its
+// encoded literals decode to, located at the outermost literal. Names known
+// reports true for are hosts whatever their TLD.
+func fileEndpoints(src *ruleSource, text bool, maxDecodeDepth int, known func(host string) bool) []networkEndpoint {
+	endpoints := []networkEndpoint{}
+	if text {
+		endpoints = append(endpoints, textEndpoints(src.content, 0, known)...)
+	} else {
+		src.lex()
+		modules := 0
+		for _, t := range src.tokens {
+			if t.kind != jsString && t.kind != jsTemplate {
+				continue
+			}
+			// Module names such as "socket.io" are not hosts.
+			for modules < len(src.modules) && src.modules[modules].end <= t.start {
+				modules++
+			}
+			if modules < len(src.modules) && src.modules[modules].start <= t.start {
+				continue
+			}
+			start, end := literalText(src.content, t)
+			endpoints = append(endpoints, textEndpoints(src.content[start:end], start, known)...)
+		}
+	}
+
+	if maxDecodeDepth <= 0 {
+		return endpoints
+	}
+	for _, literal := range encodedLiterals(src) {
+		for _, e := range fileEndpoints(newRuleSource(literal.decoded), true, maxDecodeDepth-1, known) {
+			e.start, e.end = literal.start, literal.end
+			e.decoding = append([]string{literal.encoding}, e.decoding...)
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// textEndpoints returns the URLs, IP addresses and host names in text, which
+// starts at offset in its file. Names known reports true for are hosts
+// whatever their TLD; known may be nil.
+func textEndpoints(text []byte, offset int, known func(host string) bool) []networkEndpoint {
+	endpoints := []networkEndpoint{}
+	covered := func(start, end int) bool {
+		for _, e := range endpoints {
+			if e.kind == EndpointURL && start < e.end-offset && end > e.start-offset {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, m := range urlPattern.FindAllIndex(text, -1) {
+		value := strings.TrimRight(string(text[m[0]:m[1]]), ".,;:!?)'")
+		host := endpointHost(value)
+		if validHost(host) {
+			endpoints = append(endpoints, networkEndpoint{kind: EndpointURL, value: value, host: host, start: offset + m[0], end: offset + m[0] + len(value)})
+		}
+	}
+
+	for _, pattern := range []*regexp.Regexp{ipv4Pattern, ipv6Pattern} {
+		for _, m := range pattern.FindAllIndex(text, -1) {
+			if !isolated(text, m[0], m[1]) || covered(m[0], m[1]) {
+				continue
+			}
+			value := string(text[m[0]:m[1]])
+			if pattern == ipv4Pattern && hasAnyPrefix(value, oidPrefixes) {
+				continue
+			}
+			ip := net.ParseIP(value)
+			if ip == nil || pattern == ipv6Pattern && (ip.To4() != nil || !addressLike(value) || !globalIPv6(ip, value)) || reservedIP(ip) {
+				continue
+			}
+			endpoints = append(endpoints, networkEndpoint{kind: EndpointIP, value: value, host: value, start: offset + m[0], end: offset + m[1]})
+		}
+	}
+
+	// Host names are only recognized as whole words, with a known TLD or
+	// within a known domain. Names built by concatenation may start with a
+	// dot (x + ".oast.fun"). The domains of email addresses are not hosts
+	// the code contacts.
+	for start := 0; start < len(text); {
+		for start < len(text) && (isWordBreak(text[start]) || text[start] == '.') {
+			start++
+		}
+		end := start
+		for end < len(text) && !isWordBreak(text[end]) {
+			end++
+		}
+		word := strings.TrimRight(string(text[start:end]), ".")
+		email := start > 1 && text[start-1] == '@' && isEmailLocal(text[start-2])
+		if m := hostPattern.FindStringSubmatch(word); m != nil && !email && !covered(start, end) {
+			host := word
+			if colon := strings.IndexByte(host, ':'); colon >= 0 {
+				host = host[:colon]
+			}
+			tld := hostTLDs[m[1]] && !(fileExtensionTLDs[m[1]] && strings.Count(host, ".") == 1)
+			if validHost(host) && (tld || known != nil && known(host)) {
+				endpoints = append(endpoints, networkEndpoint{kind: EndpointHost, value: word, host: host, start: offset + start, end: offset + start + len(word)})
+			}
+		}
+		start = end
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool { return endpoints[i].start < endpoints[j].start })
+	return endpoints
+}
+
+// manifestFieldSpans returns the spans of the values of the top-level fields
+// of a package.json. Invalid JSON has no fields.
+func manifestFieldSpans(content []byte, fields map[string]bool) [][2]int {
+	spans := [][2]int{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return spans
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return spans
+		}
+		key, _ := tok.(string)
+		start := int(dec.InputOffset())
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return spans
+		}
+		if fields[key] {
+			spans = append(spans, [2]int{start, int(dec.InputOffset())})
+		}
+	}
+	return spans
+}
+
+// inSpans reports whether offset lies within one of spans.
+func inSpans(spans [][2]int, offset int) bool {
+	for _, span := range spans {
+		if offset >= span[0] && offset < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordBreak reports whether c separates the words host names are
+// recognized in.
+func isWordBreak(c byte) bool {
+	return c <= ' ' || strings.IndexByte("'\"`()[]{}<>,;=@/\\|", c) >= 0
+}
+
+// isolated reports whether text[start:end] is not part of a longer word,
+// version number or address.
+func isolated(text []byte, start, end int) bool {
+	isPart := func(c byte) bool {
+		return c == '.' || c == ':' || c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	if start > 0 && isPart(text[start-1]) {
+		return false
+	}
+	if end == len(text) || !isPart(text[end]) {
+		return true
+	}
+	// A sentence may end after an address, and a port may follow one.
+	next := byte(' ')
+	if end+1 < len(text) {
+		next = text[end+1]
+	}
+	return text[end] == '.' && !isPart(next) || text[end] == ':' && next >= '0' && next <= '9'
+}
+
+// isEmailLocal reports whether c may end the local part of an email address.
+func isEmailLocal(c byte) bool {
+	return c == '.' || c == '_' || c == '-' || c == '+' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// globalIPv6 reports whether an IPv6 address is a global unicast host
+// address (2000::/3) rather than a test literal such as "1:2:3::8" or a
+// network prefix such as "2001::".
+func globalIPv6(ip net.IP, value string) bool {
+	return ip[0]&0xe0 == 0x20 && !strings.HasSuffix(value, "::")
+}
+
+// addressLike reports whether an IPv6 address is written with enough digits
+// to be told apart from "a::b" in C++ names or Ruby constants: three groups
+// or a group of three digits or more.
+func addressLike(value string) bool {
+	groups := 0
+	for _, group := range strings.Split(value, ":") {
+		if len(group) >= 3 {
+			return true
+		}
+		if group != "" {
+			groups++
+		}
+	}
+	return groups >= 3
+}
+
+// hasAnyPrefix reports whether s starts with any of prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointHost returns the lowercase host of a URL, without the port.
+func endpointHost(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// validHost reports whether host is an address or a DNS name that can be
+// contacted, outside the reserved addresses and domains.
+func validHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return !reservedIP(ip)
+	}
+	if !hostPattern.MatchString(host) {
+		return false
+	}
+	for _, domain := range reservedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// reservedIP reports whether ip is in one of the reservedNetworks.
+func reservedIP(ip net.IP) bool {
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// exfilService returns the ExfilService an endpoint belongs to. Services
+// restricted to a path only match URLs.
+func exfilService(kind, value, host string) (ExfilService, bool) {
+	for _, service := range ExfilServices {
+		if host != service.Domain && !strings.HasSuffix(host, "."+service.Domain) {
+			continue
+		}
+		if service.Path == "" {
+			return service, true
+		}
+		if kind == EndpointURL {
+			if u, err := url.Parse(value); err == nil && strings.HasPrefix(u.Path, service.Path) {
+				return service, true
+			}
+		}
+	}
+	return ExfilService{}, false
+}
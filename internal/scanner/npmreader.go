@@ -4,16 +4,33 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // PackageRef represents a package with its name, version, and path.
+// Packages read from a lockfile also carry the lockfile they came from and
+// the resolved tarball URL and integrity hash recorded there; Descriptor
+// holds the requested ranges (e.g. "lodash@^4.17.0") when the lockfile keeps them.
+// Scripts, Main and Bin hold the entry points of packages read from a
+// package.json; Bin maps command names to files. Native is set for packages
+// declaring that they build or download native code: a "gypfile" or a
+// binding.gyp, node-pre-gyp "binary" settings, or the "os" and "cpu" of
+// platform-specific builds.
 type PackageRef struct {
-	Name    string
-	Version string
-	Path    string
+	Name       string
+	Version    string
+	Path       string
+	Lockfile   string
+	Resolved   string
+	Integrity  string
+	Descriptor string
+	Scripts    map[string]string
+	Main       string
+	Bin        map[string]string
+	Native     bool
 }
 
-// DependencyReader reads dependencies from node_modules and package.json.
+// DependencyReader reads dependencies from node_modules, package.json and lockfiles.
 type DependencyReader struct{}
 
 // NewDependencyReader creates a new DependencyReader.
@@ -21,24 +38,48 @@ func NewDependencyReader() *DependencyReader {
 	return &DependencyReader{}
 }
 
-// ReadDependencies scans the given path for dependencies.
+// ReadDependencies scans the given path for dependencies. Project manifests
+// and lockfiles are read from the source tree, while node_modules directories
+// are handed to the installed-tree walker. Nested projects that Discover
+// returns as targets of their own are left to their own call.
 func (r *DependencyReader) ReadDependencies(path string) ([]PackageRef, error) {
 	packages := []PackageRef{}
+	visited := map[string]bool{}
 
 	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip paths with errors
 		}
 
+		if info.IsDir() && nestedTarget(path, p) {
+			return filepath.SkipDir // Read as a target of its own
+		}
 		if info.IsDir() && filepath.Base(p) == "node_modules" {
-			return filepath.SkipDir // Skip nested node_modules
+			packages = append(packages, readInstalled(p, visited)...)
+			return filepath.SkipDir
 		}
 
-		if filepath.Base(p) == "package.json" {
+		switch filepath.Base(p) {
+		case "package.json":
 			pkg, err := parsePackageJSON(p)
 			if err == nil {
 				packages = append(packages, pkg)
 			}
+		case "package-lock.json", "npm-shrinkwrap.json":
+			pkgs, err := parsePackageLock(p)
+			if err == nil {
+				packages = append(packages, pkgs...)
+			}
+		case "yarn.lock":
+			pkgs, err := parseYarnLock(p)
+			if err == nil {
+				packages = append(packages, pkgs...)
+			}
+		case "pnpm-lock.yaml":
+			pkgs, err := parsePnpmLock(p)
+			if err == nil {
+				packages = append(packages, pkgs...)
+			}
 		}
 		return nil
 	})
@@ -47,10 +88,131 @@ func (r *DependencyReader) ReadDependencies(path string) ([]PackageRef, error) {
 		return nil, err
 	}
 
-	return packages, nil
+	return dedupePackages(packages), nil
+}
+
+// dedupePackages merges the references to the same package, such as an
+// installed package that is also listed in the lockfile, so it is checked
+// once with the details of both. Lockfile entries merge with the installed
+// package at the same path, or else with one of the same name and version,
+// since yarn and pnpm do not record where they install packages.
+func dedupePackages(packages []PackageRef) []PackageRef {
+	deduped := []PackageRef{}
+	seen := map[string]int{}      // Name, version and path
+	installed := map[string]int{} // Name and version of installed packages
+
+	// Installed packages go first so lockfile entries can merge with them.
+	ordered := make([]PackageRef, 0, len(packages))
+	for _, fromLockfile := range []bool{false, true} {
+		for _, pkg := range packages {
+			if (pkg.Lockfile != "") == fromLockfile {
+				ordered = append(ordered, pkg)
+			}
+		}
+	}
+
+	for _, pkg := range ordered {
+		nameVersion := pkg.Name + "\x00" + pkg.Version
+		key := nameVersion + "\x00" + pkg.Path
+		i, ok := seen[key]
+		if !ok && pkg.Lockfile != "" {
+			i, ok = installed[nameVersion]
+		}
+		if !ok {
+			seen[key] = len(deduped)
+			if _, dup := installed[nameVersion]; !dup && pkg.Lockfile == "" {
+				installed[nameVersion] = len(deduped)
+			}
+			deduped = append(deduped, pkg)
+			continue
+		}
+
+		kept := &deduped[i]
+		for _, field := range []struct{ dst, src *string }{
+			{&kept.Lockfile, &pkg.Lockfile},
+			{&kept.Resolved, &pkg.Resolved},
+			{&kept.Integrity, &pkg.Integrity},
+			{&kept.Descriptor, &pkg.Descriptor},
+			{&kept.Main, &pkg.Main},
+		} {
+			if *field.dst == "" {
+				*field.dst = *field.src
+			}
+		}
+		if kept.Scripts == nil {
+			kept.Scripts = pkg.Scripts
+		}
+		if kept.Bin == nil {
+			kept.Bin = pkg.Bin
+		}
+		kept.Native = kept.Native || pkg.Native
+	}
+
+	return deduped
+}
+
+// readInstalled reads the packages installed in a node_modules directory,
+// including scoped packages, nested node_modules and the pnpm virtual store.
+// Symlinked packages are followed, and visited holds the real paths already
+// read so each package is reported once.
+func readInstalled(dir string, visited map[string]bool) []PackageRef {
+	packages := []PackageRef{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return packages
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		full := filepath.Join(dir, name)
+
+		switch {
+		case name == ".pnpm":
+			stores, _ := os.ReadDir(full)
+			for _, store := range stores {
+				packages = append(packages, readInstalled(filepath.Join(full, store.Name(), "node_modules"), visited)...)
+			}
+		case strings.HasPrefix(name, "."):
+			continue // .bin, .cache, .package-lock.json
+		case strings.HasPrefix(name, "@"):
+			scoped, _ := os.ReadDir(full)
+			for _, pkg := range scoped {
+				packages = append(packages, readInstalledPackage(filepath.Join(full, pkg.Name()), visited)...)
+			}
+		default:
+			packages = append(packages, readInstalledPackage(full, visited)...)
+		}
+	}
+
+	return packages
+}
+
+// readInstalledPackage reads one installed package and its nested node_modules.
+func readInstalledPackage(dir string, visited map[string]bool) []PackageRef {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		real = dir
+	}
+	if visited[real] {
+		return nil
+	}
+	visited[real] = true
+
+	packages := []PackageRef{}
+	if pkg, err := parsePackageJSON(filepath.Join(dir, "package.json")); err == nil {
+		packages = append(packages, pkg)
+	}
+	return append(packages, readInstalled(filepath.Join(dir, "node_modules"), visited)...)
 }
 
-// parsePackageJSON parses a package.json file and extracts the name and version.
+// parsePackageJSON parses a package.json file and extracts the name, version
+// and entry points.
 func parsePackageJSON(path string) (PackageRef, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -59,17 +221,68 @@ func parsePackageJSON(path string) (PackageRef, error) {
 	defer file.Close()
 
 	var data struct {
-		Name    string `json:"name"`
-		Version string `json:"version"`
+		Name      string         `json:"name"`
+		Version   string         `json:"version"`
+		Resolved  string         `json:"_resolved"`  // Written by npm 6 and older
+		Integrity string         `json:"_integrity"` // Written by npm 6 and older
+		Scripts   map[string]any `json:"scripts"`
+		Main      any            `json:"main"`
+		Bin       any            `json:"bin"`
+		GypFile   any            `json:"gypfile"`
+		Binary    any            `json:"binary"`
+		OS        any            `json:"os"`
+		CPU       any            `json:"cpu"`
 	}
 
 	if err := json.NewDecoder(file).Decode(&data); err != nil {
 		return PackageRef{}, err
 	}
 
+	// A malformed script must not hide the rest of the manifest.
+	var scripts map[string]string
+	for name, value := range data.Scripts {
+		if command, ok := value.(string); ok {
+			if scripts == nil {
+				scripts = map[string]string{}
+			}
+			scripts[name] = command
+		}
+	}
+
+	// "bin" is either one file named after the package or a map of commands.
+	var bin map[string]string
+	switch value := data.Bin.(type) {
+	case string:
+		command := data.Name[strings.LastIndex(data.Name, "/")+1:] // Scope dropped
+		bin = map[string]string{command: value}
+	case map[string]any:
+		for command, file := range value {
+			if file, ok := file.(string); ok {
+				if bin == nil {
+					bin = map[string]string{}
+				}
+				bin[command] = file
+			}
+		}
+	}
+
+	main, _ := data.Main.(string)
+
+	// npm builds packages with a binding.gyp even without "gypfile".
+	native := data.GypFile == true || data.Binary != nil || data.OS != nil || data.CPU != nil
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "binding.gyp")); err == nil {
+		native = true
+	}
+
 	return PackageRef{
-		Name:    data.Name,
-		Version: data.Version,
-		Path:    filepath.Dir(path),
+		Name:      data.Name,
+		Version:   data.Version,
+		Path:      filepath.Dir(path),
+		Resolved:  data.Resolved,
+		Integrity: data.Integrity,
+		Scripts:   scripts,
+		Main:      main,
+		Bin:       bin,
+		Native:    native,
 	}, nil
 }
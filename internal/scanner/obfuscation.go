@@ -0,0 +1,292 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultObfuscationMinScore is the obfuscation score from which files are
+// reported.
+const DefaultObfuscationMinScore = 0.5
+
+// DefaultMaxLineLength is the line length above which a line counts as
+// abnormally long outside minified bundles.
+const DefaultMaxLineLength = 1000
+
+// DefaultMinifiedGlobs select the files expected to be minified bundles,
+// whose long lines are not suspicious on their own.
+var DefaultMinifiedGlobs = []string{"*.min.js", "*-min.js", "*.bundle.js", "**/dist/**", "**/build/**", "**/umd/**"}
+
+// hexIdentifier matches the identifiers javascript-obfuscator generates.
+var hexIdentifier = regexp.MustCompile(`^_0x[0-9a-fA-F]{4,}$`)
+
+// stringArrayRotation matches the statement that rotates an obfuscator's
+// string array into place, a.push(a.shift()), in dot or bracket notation.
+// Moving items between two arrays is common and does not count.
+var stringArrayRotation = regexp.MustCompile(`([\w$]+)(?:\.push|\[\s*['"]push['"]\s*\])\(\s*([\w$]+)(?:\.shift|\[\s*['"]shift['"]\s*\])\(\)\s*\)`)
+
+// encodedBlob matches string literal text that could be an encoded payload:
+// ASCII without whitespace, unlike prose or tables of characters.
+var encodedBlob = regexp.MustCompile(`^[!-~]+$`)
+
+// stringEscape matches a hex or Unicode escape in a string literal.
+var stringEscape = regexp.MustCompile(`\\x[0-9a-fA-F]{2}|\\u[0-9a-fA-F]{4}`)
+
+// Thresholds of the obfuscation signals.
+const (
+	minHexIdentifiers   = 3
+	manyHexIdentifiers  = 10
+	minArrayStrings     = 30
+	minEntropyLength    = 80
+	highStringEntropy   = 5.0
+	highLineEntropy     = 5.2
+	minStringEscapes    = 100
+	minHexNumbers       = 50
+	hexNumberProportion = 0.5
+)
+
+// ObfuscationDetector scores how obfuscated scanned files look, from the
+// identifiers, string arrays, escapes, entropy and line lengths typical of
+// obfuscator output and packed payloads. Files scoring MinScore or more (0 to
+// 1) are reported. Lines longer than MaxLineLength count against files not
+// matched by MinifiedGlobs, which are globs as for IoCScanner.Include.
+type ObfuscationDetector struct {
+	MinScore      float64
+	MaxLineLength int
+	MinifiedGlobs []string
+
+	minified []*regexp.Regexp
+}
+
+// NewObfuscationDetector creates an ObfuscationDetector with the default
+// score threshold, line length and minified bundle globs.
+func NewObfuscationDetector() *ObfuscationDetector {
+	d := &ObfuscationDetector{
+		MinScore:      DefaultObfuscationMinScore,
+		MaxLineLength: DefaultMaxLineLength,
+		MinifiedGlobs: DefaultMinifiedGlobs,
+	}
+	d.buildIndex()
+	return d
+}
+
+// buildIndex compiles the minified bundle globs.
+func (d *ObfuscationDetector) buildIndex() {
+	d.minified = compileGlobs(d.MinifiedGlobs)
+}
+
+// obfuscationSignal is one sign of obfuscation found in a file, located at
+// its most telling instance.
+type obfuscationSignal struct {
+	detail     string
+	weight     float64
+	start, end int
+}
+
+// Analyze reports the file at path, whose path relative to the scanned root
+// is rel, when its content looks obfuscated.
+func (d *ObfuscationDetector) Analyze(path, rel string, content []byte) []Finding {
+	return d.analyze(path, rel, newRuleSource(content))
+}
+
+// analyze scores a scanned file; a nil detector reports nothing.
+func (d *ObfuscationDetector) analyze(path, rel string, src *ruleSource) []Finding {
+	findings := []Finding{}
+	if d == nil {
+		return findings
+	}
+	if d.minified == nil {
+		d.buildIndex()
+	}
+
+	signals := d.signals(rel, src)
+	if len(signals) == 0 {
+		return findings
+	}
+
+	// Signals are independent evidence, so they combine like probabilities.
+	clean := 1.0
+	for _, signal := range signals {
+		clean *= 1 - signal.weight
+	}
+	score := math.Round((1-clean)*100) / 100
+	if score < d.MinScore {
+		return findings
+	}
+
+	sort.SliceStable(signals, func(i, j int) bool { return signals[i].weight > signals[j].weight })
+	details := make([]string, len(signals))
+	for i, signal := range signals {
+		details[i] = signal.detail
+	}
+
+	severity := SeverityMedium
+	if score >= 0.8 {
+		severity = SeverityHigh
+	} else if score < 0.6 {
+		severity = SeverityLow
+	}
+
+	top := signals[0]
+	line, column := positionAt(src.content, top.start)
+	return append(findings, Finding{
+		Type:        "obfuscation",
+		File:        path,
+		Reason:      fmt.Sprintf("Code looks obfuscated (score %.2f)", score),
+		Evidence:    strings.Join(details, "; "),
+		Line:        line,
+		Column:      column,
+		Offset:      top.start,
+		Length:      top.end - top.start,
+		Snippet:     snippetAt(src.content, top.start, top.end),
+		Confidence:  score,
+		Severity:    severity,
+		RuleID:      "obfuscation",
+		Remediation: "Deobfuscate the file and review what it does, or remove the package unless its obfuscation is expected",
+	})
+}
+
+// signals returns the signs of obfuscation in a file.
+func (d *ObfuscationDetector) signals(rel string, src *ruleSource) []obfuscationSignal {
+	src.lex()
+	content := src.content
+	signals := []obfuscationSignal{}
+
+	hexNames := map[string]bool{}
+	firstHexName := jsToken{}
+	numbers, hexNumbers := 0, 0
+	firstHexNumber := jsToken{}
+	escapes := 0
+	firstEscape := jsToken{}
+	highEntropy := 0
+	maxEntropy, maxEntropyToken := 0.0, jsToken{}
+	arrayStrings, maxArrayStrings := 0, 0
+	arrayStart, maxArray := 0, jsToken{}
+
+	for i, t := range src.tokens {
+		text := content[t.start:t.end]
+		switch t.kind {
+		case jsIdentifier:
+			if hexIdentifier.Match(text) {
+				if len(hexNames) == 0 {
+					firstHexName = t
+				}
+				hexNames[string(text)] = true
+			}
+		case jsNumber:
+			numbers++
+			if len(text) > 2 && text[0] == '0' && (text[1] == 'x' || text[1] == 'X') {
+				if hexNumbers == 0 {
+					firstHexNumber = t
+				}
+				hexNumbers++
+			}
+		case jsString, jsTemplate:
+			if n := len(stringEscape.FindAllIndex(text, -1)); n > 0 {
+				if escapes == 0 {
+					firstEscape = t
+				}
+				escapes += n
+			}
+			start, end := literalText(content, t)
+			if end-start >= minEntropyLength && encodedBlob.Match(content[start:end]) && !bytes.HasPrefix(content[start:end], []byte("data:")) {
+				if entropy := shannonEntropy(content[start:end]); entropy >= highStringEntropy {
+					highEntropy++
+					if entropy > maxEntropy {
+						maxEntropy, maxEntropyToken = entropy, t
+					}
+				}
+			}
+		}
+
+		// Count the strings of an array literal, such as ['a', 'b', ...].
+		switch {
+		case t.kind == jsPunct && content[t.start] == '[':
+			arrayStrings, arrayStart = 0, t.start
+		case t.kind == jsString && i > 0 && src.tokens[i-1].kind == jsPunct && (content[src.tokens[i-1].start] == '[' || content[src.tokens[i-1].start] == ','):
+			arrayStrings++
+			if arrayStrings > maxArrayStrings {
+				maxArrayStrings, maxArray = arrayStrings, jsToken{start: arrayStart, end: t.end}
+			}
+		case t.kind != jsPunct || content[t.start] != ',':
+			arrayStrings = 0
+		}
+	}
+
+	if n := len(hexNames); n >= minHexIdentifiers {
+		weight := 0.4
+		if n >= manyHexIdentifiers {
+			weight = 0.7
+		}
+		signals = append(signals, obfuscationSignal{fmt.Sprintf("%d _0x identifiers", n), weight, firstHexName.start, firstHexName.end})
+	}
+	for _, m := range stringArrayRotation.FindAllSubmatchIndex(content, -1) {
+		if bytes.Equal(content[m[2]:m[3]], content[m[4]:m[5]]) {
+			signals = append(signals, obfuscationSignal{"string array rotation", 0.6, m[0], m[1]})
+			break
+		}
+	}
+	if maxArrayStrings >= minArrayStrings {
+		signals = append(signals, obfuscationSignal{fmt.Sprintf("array of %d strings", maxArrayStrings), 0.3, maxArray.start, maxArray.start + 1})
+	}
+	if highEntropy > 0 {
+		signals = append(signals, obfuscationSignal{fmt.Sprintf("%d high-entropy strings (up to %.1f bits per byte)", highEntropy, maxEntropy), 0.35, maxEntropyToken.start, maxEntropyToken.end})
+	}
+	if escapes >= minStringEscapes {
+		signals = append(signals, obfuscationSignal{fmt.Sprintf("%d hex or Unicode escapes in strings", escapes), 0.4, firstEscape.start, firstEscape.end})
+	}
+	if hexNumbers >= minHexNumbers && float64(hexNumbers) >= hexNumberProportion*float64(numbers) {
+		signals = append(signals, obfuscationSignal{fmt.Sprintf("%d hex numeric literals", hexNumbers), 0.3, firstHexNumber.start, firstHexNumber.end})
+	}
+
+	if d.MaxLineLength > 0 && !matchGlobs(d.minified, rel) {
+		long, longest, longestStart := 0, 0, 0
+		for start := 0; start < len(content); {
+			end := len(content)
+			if i := bytes.IndexByte(content[start:], '\n'); i >= 0 {
+				end = start + i
+			}
+			if end-start > d.MaxLineLength && !bytes.HasPrefix(content[start:end], []byte("//# sourceMappingURL=")) {
+				long++
+				if end-start > longest {
+					longest, longestStart = end-start, start
+				}
+			}
+			start = end + 1
+		}
+		if long > 0 {
+			entropy := shannonEntropy(content[longestStart : longestStart+longest])
+			weight := 0.3
+			if entropy >= highLineEntropy {
+				weight = 0.4
+			}
+			signals = append(signals, obfuscationSignal{fmt.Sprintf("%d lines over %d bytes (longest %d, %.1f bits per byte)", long, d.MaxLineLength, longest, entropy), weight, longestStart, longestStart + longest})
+		}
+	}
+
+	return signals
+}
+
+// shannonEntropy returns the Shannon entropy of data in bits per byte: about
+// 4 for English text and code, up to 6 for base64 and 8 for random bytes.
+func shannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
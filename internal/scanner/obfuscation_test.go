@@ -0,0 +1,167 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// obfuscatorOutput imitates the output of javascript-obfuscator.
+func obfuscatorOutput() string {
+	var b strings.Builder
+	b.WriteString("var _0x4a2c=[")
+	for i := 0; i < 40; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "'\\x%02x\\x%02x\\x%02x'", 0x61+i%26, 0x62+i%20, 0x63+i%10)
+	}
+	b.WriteString("];\n(function(_0x1b3f09,_0x4a2c5e){var _0x2d8f1a=function(_0x5c9e2b){while(--_0x5c9e2b){_0x1b3f09['push'](_0x1b3f09['shift']());}};_0x2d8f1a(++_0x4a2c5e);}(_0x4a2c,0x1f3));\n")
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&b, "var _0x%04x=_0x%04x(0x%x);\n", 0x1000+i, 0x2000+i, i+0x10)
+	}
+	return b.String()
+}
+
+// randomBase64 returns n bytes of base64 text of random data.
+func randomBase64(n int) string {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return base64.StdEncoding.EncodeToString(data)[:n]
+}
+
+func TestObfuscationDetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		minScore float64
+		signals  []string // Expected in the evidence; nil for no finding
+		severity string
+	}{
+		{
+			name:     "obfuscator output",
+			file:     "index.js",
+			content:  obfuscatorOutput(),
+			signals:  []string{"125 _0x identifiers", "string array rotation", "array of 40 strings", "hex numeric literals"},
+			severity: SeverityHigh,
+		},
+		{
+			name:    "plain code",
+			file:    "index.js",
+			content: "const fs = require('fs')\n// Read the configuration\nmodule.exports = function load(path) {\n  return JSON.parse(fs.readFileSync(path, 'utf8'))\n}\n",
+		},
+		{
+			name:    "high-entropy string alone",
+			file:    "index.js",
+			content: "const key = '" + randomBase64(200) + "'\n",
+		},
+		{
+			name:     "high-entropy string with a lower threshold",
+			file:     "index.js",
+			content:  "const key = '" + randomBase64(200) + "'\n",
+			minScore: 0.3,
+			signals:  []string{"1 high-entropy strings"},
+			severity: SeverityLow,
+		},
+		{
+			name:    "data URI",
+			file:    "index.js",
+			content: "const icon = 'data:image/png;base64," + randomBase64(200) + "'\n",
+		},
+		{
+			name:     "long packed line",
+			file:     "lib/payload.js",
+			content:  "eval(atob('" + randomBase64(3000) + "'))\n",
+			signals:  []string{"1 lines over 1000 bytes", "1 high-entropy strings"},
+			severity: SeverityMedium,
+		},
+		{
+			name:    "minified bundle",
+			file:    "dist/app.js",
+			content: strings.Repeat("function a(b){return b+1};", 100) + "\n",
+		},
+		{
+			name:     "escaped strings",
+			file:     "index.js",
+			content:  "var s = '" + strings.Repeat("\\x63\\u0068", 60) + "'\n" + strings.Repeat("x", 1200) + "\n",
+			signals:  []string{"120 hex or Unicode escapes", "1 lines over 1000 bytes"},
+			severity: SeverityLow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewObfuscationDetector()
+			if tt.minScore > 0 {
+				d.MinScore = tt.minScore
+			}
+			findings := d.Analyze(filepath.FromSlash(tt.file), tt.file, []byte(tt.content))
+
+			if tt.signals == nil {
+				if len(findings) != 0 {
+					t.Errorf("Expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("Expected 1 finding, got %d", len(findings))
+			}
+			f := findings[0]
+			for _, signal := range tt.signals {
+				if !strings.Contains(f.Evidence, signal) {
+					t.Errorf("Expected signal %q in %q", signal, f.Evidence)
+				}
+			}
+			if f.Type != "obfuscation" || f.RuleID != "obfuscation" || f.Severity != tt.severity {
+				t.Errorf("Expected an obfuscation finding of severity %s, got %+v", tt.severity, f)
+			}
+			if f.Confidence < d.MinScore || f.Confidence > 1 || f.Line < 1 || f.Length == 0 {
+				t.Errorf("Expected a score and a location, got %+v", f)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_Obfuscation(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.js"), []byte(obfuscatorOutput()), 0644)
+
+	scanner, err := NewIoCScanner(nil, 10)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	findings, _ := scanner.Scan(dir)
+	if len(findings) != 0 {
+		t.Errorf("Expected no findings without a detector, got %d", len(findings))
+	}
+
+	scanner.Obfuscation = NewObfuscationDetector()
+	findings, _ = scanner.Scan(dir)
+	if len(findings) != 1 || findings[0].File != filepath.Join(dir, "index.js") {
+		t.Errorf("Expected the obfuscated file to be reported, got %+v", findings)
+	}
+}
+
+func TestShannonEntropy(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected float64
+	}{
+		{"", 0},
+		{"aaaa", 0},
+		{"abab", 1},
+		{"abcdefgh", 3},
+	}
+
+	for _, tt := range tests {
+		if got := shannonEntropy([]byte(tt.data)); math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("Expected entropy %v for %q, got %v", tt.expected, tt.data, got)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package scanner
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// osvRecord is the subset of the OSV schema (https://ossf.github.io/osv-schema/)
+// needed to build blocklist entries.
+type osvRecord struct {
+	ID         string         `json:"id"`
+	Aliases    []string       `json:"aliases"`
+	Summary    string         `json:"summary"`
+	Published  string         `json:"published"`
+	Withdrawn  string         `json:"withdrawn"`
+	Affected   []osvAffected  `json:"affected"`
+	References []osvReference `json:"references"`
+
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges   []osvRange `json:"ranges"`
+	Versions []string   `json:"versions"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"`
+	Events []osvEvent `json:"events"`
+}
+
+type osvEvent struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+	Limit        string `json:"limit"`
+}
+
+type osvReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// LoadOSV loads npm advisories in OSV format from a directory of JSON records
+// or a zip archive of them, such as the OpenSSF malicious-packages dataset.
+// JSON files that are not OSV records are logged and skipped.
+func LoadOSV(path string) (*Blocklist, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []BlocklistEntry{}
+	add := func(name string, r io.Reader) error {
+		var record osvRecord
+		if err := json.NewDecoder(r).Decode(&record); err != nil {
+			log.Printf("Warning: Skipping %s, not an OSV record: %v", name, err)
+			return nil
+		}
+		for _, entry := range osvEntries(record) {
+			entry.Sources = []string{name}
+			entries = append(entries, entry)
+		}
+		return nil
+	}
+
+	if info.IsDir() {
+		err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() || !strings.HasSuffix(p, ".json") {
+				return err
+			}
+			file, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return add(p, file)
+		})
+	} else {
+		err = loadOSVZip(path, add)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newBlocklist(entries), nil
+}
+
+// loadOSVZip feeds every JSON record of a zip archive to add.
+func loadOSVZip(path string, add func(string, io.Reader) error) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = add(path+"/"+f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// osvEntries converts the npm packages affected by an OSV record into
+// blocklist entries carrying the advisory metadata.
+func osvEntries(record osvRecord) []BlocklistEntry {
+	if record.Withdrawn != "" {
+		return nil
+	}
+
+	var references []string
+	for _, ref := range record.References {
+		references = append(references, ref.URL)
+	}
+
+	entries := []BlocklistEntry{}
+	for _, affected := range record.Affected {
+		if !strings.EqualFold(affected.Package.Ecosystem, "npm") || affected.Package.Name == "" {
+			continue
+		}
+
+		versions, all, ok := osvVersions(affected)
+		if !ok {
+			continue
+		}
+		if all {
+			versions = []string{}
+		}
+
+		entries = append(entries, BlocklistEntry{
+			Name:        affected.Package.Name,
+			Versions:    versions,
+			ID:          record.ID,
+			Aliases:     record.Aliases,
+			Severity:    normalizeSeverity(record.DatabaseSpecific.Severity, ""),
+			Summary:     record.Summary,
+			References:  references,
+			Published:   record.Published,
+			SafeVersion: osvSafeVersion(affected),
+		})
+	}
+	return entries
+}
+
+// osvVersions converts the affected versions and SEMVER/ECOSYSTEM ranges of a
+// package into blocklist versions and ranges. all is true when every version
+// is affected, as for most malicious packages ("introduced": "0" with no end)
+// and packages listed without versions or ranges. ok is false when only
+// other ranges, such as GIT commit ranges, say which versions are affected.
+func osvVersions(affected osvAffected) (versions []string, all, ok bool) {
+	versions = append([]string{}, affected.Versions...)
+
+	hasRanges, otherRanges := false, false
+	for _, r := range affected.Ranges {
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			otherRanges = true
+			continue // GIT ranges cannot be mapped to published versions
+		}
+		hasRanges = true
+
+		introduced := ""
+		open := false
+		for _, event := range r.Events {
+			switch {
+			case event.Introduced != "":
+				introduced, open = event.Introduced, true
+			case event.Fixed != "" || event.Limit != "":
+				end := event.Fixed
+				if end == "" {
+					end = event.Limit
+				}
+				versions = append(versions, osvBound(introduced, "<"+end))
+				open = false
+			case event.LastAffected != "":
+				versions = append(versions, osvBound(introduced, "<="+event.LastAffected))
+				open = false
+			}
+		}
+
+		if open {
+			if introduced == "0" {
+				return nil, true, true
+			}
+			versions = append(versions, ">="+introduced)
+		}
+	}
+
+	if !hasRanges && len(versions) == 0 {
+		return nil, !otherRanges, !otherRanges
+	}
+	return versions, false, true
+}
+
+// osvSafeVersion returns the version that fixes the package when no later
+// release is affected again.
+func osvSafeVersion(affected osvAffected) string {
+	safe := ""
+	for _, r := range affected.Ranges {
+		if r.Type == "GIT" || len(r.Events) == 0 {
+			continue
+		}
+		last := r.Events[len(r.Events)-1]
+		if last.Fixed == "" {
+			return ""
+		}
+		safe = last.Fixed
+	}
+	return safe
+}
+
+// osvBound joins an introduced version and an upper bound into a range.
+func osvBound(introduced, upper string) string {
+	if introduced == "" || introduced == "0" {
+		return upper
+	}
+	return ">=" + introduced + " " + upper
+}
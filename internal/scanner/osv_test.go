@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const osvMalicious = `{
+	"id": "MAL-2022-1234",
+	"aliases": ["GHSA-xxxx-yyyy-zzzz"],
+	"summary": "Malicious code in flatmap-stream (npm)",
+	"affected": [{
+		"package": {"ecosystem": "npm", "name": "flatmap-stream"},
+		"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+	}],
+	"references": [{"type": "WEB", "url": "https://example.com/advisory"}]
+}`
+
+const osvRanged = `{
+	"id": "GHSA-aaaa-bbbb-cccc",
+	"summary": "Compromised releases of event-stream",
+	"affected": [
+		{
+			"package": {"ecosystem": "npm", "name": "event-stream"},
+			"ranges": [
+				{"type": "SEMVER", "events": [{"introduced": "3.3.6"}, {"fixed": "4.0.0"}, {"introduced": "4.0.1"}, {"last_affected": "4.0.2"}]},
+				{"type": "GIT", "events": [{"introduced": "abc123"}]}
+			],
+			"versions": ["3.3.6"]
+		},
+		{"package": {"ecosystem": "PyPI", "name": "event-stream"}, "versions": ["1.0.0"]}
+	]
+}`
+
+const osvGitOnly = `{
+	"id": "GHSA-dddd-eeee-ffff",
+	"affected": [{
+		"package": {"ecosystem": "npm", "name": "lodash"},
+		"ranges": [{"type": "GIT", "repo": "https://github.com/lodash/lodash", "events": [{"introduced": "abc123"}, {"fixed": "def456"}]}]
+	}]
+}`
+
+const osvWithdrawn = `{
+	"id": "MAL-2022-0001",
+	"withdrawn": "2022-06-01T00:00:00Z",
+	"affected": [{"package": {"ecosystem": "npm", "name": "left-pad"}}]
+}`
+
+func TestLoadOSV(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "npm", "flatmap-stream"), 0755)
+	os.WriteFile(filepath.Join(dir, "npm", "flatmap-stream", "MAL-2022-1234.json"), []byte(osvMalicious), 0644)
+	os.WriteFile(filepath.Join(dir, "GHSA-aaaa-bbbb-cccc.json"), []byte(osvRanged), 0644)
+	os.WriteFile(filepath.Join(dir, "MAL-2022-0001.json"), []byte(osvWithdrawn), 0644)
+	os.WriteFile(filepath.Join(dir, "GHSA-dddd-eeee-ffff.json"), []byte(osvGitOnly), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a record"), 0644)
+	os.WriteFile(filepath.Join(dir, "index.json"), []byte(`["MAL-2022-1234"]`), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644)
+
+	expected := []BlocklistEntry{
+		{
+			Name:     "event-stream",
+			Versions: []string{"3.3.6", ">=3.3.6 <4.0.0", ">=4.0.1 <=4.0.2"},
+			ID:       "GHSA-aaaa-bbbb-cccc",
+			Summary:  "Compromised releases of event-stream",
+		},
+		{
+			Name:       "flatmap-stream",
+			Versions:   []string{},
+			ID:         "MAL-2022-1234",
+			Aliases:    []string{"GHSA-xxxx-yyyy-zzzz"},
+			Summary:    "Malicious code in flatmap-stream (npm)",
+			References: []string{"https://example.com/advisory"},
+		},
+	}
+
+	blocklist, err := LoadOSV(dir)
+	if err != nil {
+		t.Fatalf("LoadOSV failed: %v", err)
+	}
+	assertOSVEntries(t, blocklist, expected)
+
+	// The same records packed in a zip archive.
+	zipPath := filepath.Join(t.TempDir(), "osv.zip")
+	file, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to create zip: %v", err)
+	}
+	archive := zip.NewWriter(file)
+	for name, content := range map[string]string{"GHSA-aaaa-bbbb-cccc.json": osvRanged, "npm/MAL-2022-1234.json": osvMalicious} {
+		w, _ := archive.Create(name)
+		w.Write([]byte(content))
+	}
+	archive.Close()
+	file.Close()
+
+	blocklist, err = LoadOSV(zipPath)
+	if err != nil {
+		t.Fatalf("LoadOSV failed for zip: %v", err)
+	}
+	assertOSVEntries(t, blocklist, expected)
+
+	findings := blocklist.Match(PackageRef{Name: "event-stream", Version: "4.0.2"})
+	if len(findings) != 1 || findings[0].Advisory != "GHSA-aaaa-bbbb-cccc" {
+		t.Errorf("Expected a finding citing GHSA-aaaa-bbbb-cccc, got %+v", findings)
+	}
+
+	if _, err := LoadOSV(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing path, got nil")
+	}
+
+	if findings := blocklist.Match(PackageRef{Name: "lodash", Version: "4.17.21"}); len(findings) != 0 {
+		t.Errorf("Expected an advisory with only GIT ranges not to block every version, got %+v", findings)
+	}
+}
+
+func assertOSVEntries(t *testing.T, blocklist *Blocklist, expected []BlocklistEntry) {
+	t.Helper()
+
+	got := map[string]BlocklistEntry{}
+	for _, entry := range blocklist.Entries {
+		if len(entry.Sources) != 1 || !strings.HasSuffix(entry.Sources[0], ".json") {
+			t.Errorf("Expected %s to record its source record, got %v", entry.Name, entry.Sources)
+		}
+		entry.Sources = nil
+		got[entry.Name] = entry
+	}
+	if len(blocklist.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d: %+v", len(expected), len(blocklist.Entries), blocklist.Entries)
+	}
+	for _, want := range expected {
+		if !reflect.DeepEqual(got[want.Name], want) {
+			t.Errorf("Expected entry %+v, got %+v", want, got[want.Name])
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packageLock is the subset of package-lock.json / npm-shrinkwrap.json we read.
+// lockfileVersion 1 only has "dependencies", version 3 only has "packages",
+// and version 2 carries both for backwards compatibility.
+type packageLock struct {
+	LockfileVersion int                         `json:"lockfileVersion"`
+	Packages        map[string]lockPackage      `json:"packages"`
+	Dependencies    map[string]lockV1Dependency `json:"dependencies"`
+}
+
+// lockPackage is an entry of the "packages" map (lockfileVersion 2 and 3).
+type lockPackage struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Resolved  string `json:"resolved"`
+	Integrity string `json:"integrity"`
+	Link      bool   `json:"link"`
+}
+
+// lockV1Dependency is an entry of the nested "dependencies" tree (lockfileVersion 1).
+type lockV1Dependency struct {
+	Version      string                      `json:"version"`
+	Resolved     string                      `json:"resolved"`
+	Integrity    string                      `json:"integrity"`
+	Dependencies map[string]lockV1Dependency `json:"dependencies"`
+}
+
+// parsePackageLock parses an npm lockfile and returns every resolved package in it.
+func parsePackageLock(path string) ([]PackageRef, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lock packageLock
+	if err := json.NewDecoder(file).Decode(&lock); err != nil {
+		return nil, err
+	}
+
+	root := filepath.Dir(path)
+	if len(lock.Packages) > 0 {
+		return lockPackages(lock.Packages, root, path), nil
+	}
+	return lockV1Packages(lock.Dependencies, root, "", path), nil
+}
+
+// lockPackages converts the "packages" map, keyed by install location such as
+// "node_modules/a/node_modules/@scope/b", into package references.
+func lockPackages(entries map[string]lockPackage, root, lockfile string) []PackageRef {
+	packages := []PackageRef{}
+
+	for _, key := range sortedKeys(entries) {
+		entry := entries[key]
+		idx := strings.LastIndex(key, "node_modules/")
+		if idx < 0 || entry.Link {
+			continue // Root project, workspace sources and symlinks
+		}
+
+		name := key[idx+len("node_modules/"):]
+		if entry.Name != "" {
+			name = entry.Name // Aliased installs record the real package name
+		}
+
+		packages = append(packages, PackageRef{
+			Name:      name,
+			Version:   entry.Version,
+			Path:      installPath(root, key),
+			Lockfile:  lockfile,
+			Resolved:  entry.Resolved,
+			Integrity: entry.Integrity,
+		})
+	}
+
+	return packages
+}
+
+// lockV1Packages walks the nested lockfileVersion 1 dependency tree.
+func lockV1Packages(deps map[string]lockV1Dependency, root, prefix, lockfile string) []PackageRef {
+	packages := []PackageRef{}
+
+	for _, key := range sortedKeys(deps) {
+		dep := deps[key]
+		location := prefix + "node_modules/" + key
+		name, version := splitAlias(key, dep.Version)
+
+		packages = append(packages, PackageRef{
+			Name:      name,
+			Version:   version,
+			Path:      installPath(root, location),
+			Lockfile:  lockfile,
+			Resolved:  dep.Resolved,
+			Integrity: dep.Integrity,
+		})
+		packages = append(packages, lockV1Packages(dep.Dependencies, root, location+"/", lockfile)...)
+	}
+
+	return packages
+}
+
+// installPath returns the directory a lockfile location such as
+// "node_modules/a" is installed to, or the project root when the package is
+// not installed there.
+func installPath(root, location string) string {
+	dir := filepath.Join(root, filepath.FromSlash(location))
+	if !fileExists(dir) {
+		return root
+	}
+	return dir
+}
+
+// splitAlias resolves an aliased version such as "npm:real-name@1.2.3" to the
+// real package name and version.
+func splitAlias(name, version string) (string, string) {
+	spec, ok := strings.CutPrefix(version, "npm:")
+	if !ok {
+		return name, version
+	}
+	if at := strings.LastIndex(spec, "@"); at > 0 {
+		return spec[:at], spec[at+1:]
+	}
+	return name, version
+}
+
+// sortedKeys returns the keys of a map in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,216 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePackageLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []PackageRef
+	}{
+		{
+			name: "lockfileVersion 1 with nested dependencies",
+			content: `{
+				"lockfileVersion": 1,
+				"dependencies": {
+					"event-stream": {
+						"version": "3.3.6",
+						"resolved": "https://registry.npmjs.org/event-stream/-/event-stream-3.3.6.tgz",
+						"integrity": "sha512-abc",
+						"dependencies": {
+							"flatmap-stream": {"version": "0.1.1", "integrity": "sha512-def"}
+						}
+					},
+					"my-alias": {"version": "npm:lodash@4.17.21"}
+				}
+			}`,
+			expected: []PackageRef{
+				{Name: "event-stream", Version: "3.3.6", Path: "node_modules/event-stream", Resolved: "https://registry.npmjs.org/event-stream/-/event-stream-3.3.6.tgz", Integrity: "sha512-abc"},
+				{Name: "flatmap-stream", Version: "0.1.1", Path: "node_modules/event-stream/node_modules/flatmap-stream", Integrity: "sha512-def"},
+				{Name: "lodash", Version: "4.17.21", Path: "node_modules/my-alias"},
+			},
+		},
+		{
+			name: "lockfileVersion 2 prefers packages",
+			content: `{
+				"lockfileVersion": 2,
+				"packages": {
+					"": {"name": "app", "version": "1.0.0"},
+					"node_modules/@scope/pkg": {"version": "2.0.0", "integrity": "sha512-xyz"}
+				},
+				"dependencies": {
+					"@scope/pkg": {"version": "2.0.0"}
+				}
+			}`,
+			expected: []PackageRef{
+				{Name: "@scope/pkg", Version: "2.0.0", Path: "node_modules/@scope/pkg", Integrity: "sha512-xyz"},
+			},
+		},
+		{
+			name: "lockfileVersion 3 with nested, aliased and linked packages",
+			content: `{
+				"lockfileVersion": 3,
+				"packages": {
+					"": {"name": "app", "version": "1.0.0"},
+					"packages/lib": {"name": "lib", "version": "0.1.0"},
+					"node_modules/lib": {"resolved": "packages/lib", "link": true},
+					"node_modules/a": {"version": "1.0.0"},
+					"node_modules/a/node_modules/b": {"version": "2.0.0", "resolved": "https://registry.npmjs.org/b/-/b-2.0.0.tgz"},
+					"node_modules/alias": {"name": "real", "version": "3.0.0"}
+				}
+			}`,
+			expected: []PackageRef{
+				{Name: "a", Version: "1.0.0", Path: "node_modules/a"},
+				{Name: "b", Version: "2.0.0", Path: "node_modules/a/node_modules/b", Resolved: "https://registry.npmjs.org/b/-/b-2.0.0.tgz"},
+				{Name: "real", Version: "3.0.0", Path: "node_modules/alias"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, want := range tt.expected {
+				os.MkdirAll(filepath.Join(dir, filepath.FromSlash(want.Path)), 0755)
+			}
+			lockPath := filepath.Join(dir, "package-lock.json")
+			if err := os.WriteFile(lockPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write lockfile: %v", err)
+			}
+
+			packages, err := parsePackageLock(lockPath)
+			if err != nil {
+				t.Fatalf("parsePackageLock failed: %v", err)
+			}
+
+			if len(packages) != len(tt.expected) {
+				t.Fatalf("Expected %d packages, got %d: %+v", len(tt.expected), len(packages), packages)
+			}
+
+			for i, want := range tt.expected {
+				want.Path = filepath.Join(dir, filepath.FromSlash(want.Path))
+				want.Lockfile = lockPath
+				if !reflect.DeepEqual(packages[i], want) {
+					t.Errorf("Package %d: expected %+v, got %+v", i, want, packages[i])
+				}
+			}
+		})
+	}
+
+	// Packages that are not installed point at the project instead.
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, "package-lock.json")
+	os.WriteFile(lockPath, []byte(`{"lockfileVersion": 3, "packages": {"node_modules/a": {"version": "1.0.0"}}}`), 0644)
+	packages, err := parsePackageLock(lockPath)
+	if err != nil || len(packages) != 1 || packages[0].Path != dir {
+		t.Errorf("Expected a at %s, got %+v (err %v)", dir, packages, err)
+	}
+
+	if _, err := parsePackageLock(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing lockfile, got nil")
+	}
+}
+
+func TestDependencyReader_Lockfiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "app", "version": "1.0.0"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "npm-shrinkwrap.json"), []byte(`{
+		"lockfileVersion": 3,
+		"packages": {"node_modules/event-stream": {"version": "3.3.6"}}
+	}`), 0644)
+
+	packages, err := NewDependencyReader().ReadDependencies(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dependencies: %v", err)
+	}
+
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d: %+v", len(packages), packages)
+	}
+
+	found := false
+	for _, pkg := range packages {
+		if pkg.Name == "event-stream" && pkg.Version == "3.3.6" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected event-stream@3.3.6 from npm-shrinkwrap.json, got %+v", packages)
+	}
+
+	// An installed package listed in the lockfile is reported once, with the
+	// lockfile details and the manifest entry points.
+	installed := filepath.Join(dir, "node_modules", "event-stream")
+	os.MkdirAll(installed, 0755)
+	os.WriteFile(filepath.Join(installed, "package.json"), []byte(`{"name": "event-stream", "version": "3.3.6", "scripts": {"postinstall": "node x.js"}}`), 0644)
+
+	packages, err = NewDependencyReader().ReadDependencies(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dependencies: %v", err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d: %+v", len(packages), packages)
+	}
+	for _, pkg := range packages {
+		if pkg.Name == "event-stream" && (pkg.Path != installed || pkg.Lockfile == "" || pkg.Scripts["postinstall"] == "") {
+			t.Errorf("Expected the lockfile and installed entries merged, got %+v", pkg)
+		}
+	}
+}
+
+func TestDependencyReader_InstalledLockfileEntries(t *testing.T) {
+	tests := []struct {
+		name      string
+		lockfile  string
+		content   string
+		installed string // Where the package manager installs evil@1.0.0
+	}{
+		{
+			name:      "package-lock",
+			lockfile:  "package-lock.json",
+			content:   `{"lockfileVersion": 3, "packages": {"node_modules/evil": {"version": "1.0.0", "integrity": "sha512-evil"}}}`,
+			installed: "node_modules/evil",
+		},
+		{
+			name:      "yarn",
+			lockfile:  "yarn.lock",
+			content:   "# yarn lockfile v1\n\nevil@^1.0.0:\n  version \"1.0.0\"\n  integrity sha512-evil\n",
+			installed: "node_modules/evil",
+		},
+		{
+			name:      "pnpm",
+			lockfile:  "pnpm-lock.yaml",
+			content:   "lockfileVersion: '6.0'\n\ndependencies:\n  evil:\n    specifier: ^1.0.0\n    version: 1.0.0\n\npackages:\n\n  /evil@1.0.0:\n    resolution: {integrity: sha512-evil}\n",
+			installed: "node_modules/.pnpm/evil@1.0.0/node_modules/evil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			installed := filepath.Join(dir, filepath.FromSlash(tt.installed))
+			os.MkdirAll(installed, 0755)
+			os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "app", "version": "1.0.0"}`), 0644)
+			os.WriteFile(filepath.Join(dir, tt.lockfile), []byte(tt.content), 0644)
+			os.WriteFile(filepath.Join(installed, "package.json"), []byte(`{"name": "evil", "version": "1.0.0"}`), 0644)
+
+			packages, err := NewDependencyReader().ReadDependencies(dir)
+			if err != nil {
+				t.Fatalf("Failed to read dependencies: %v", err)
+			}
+			if len(packages) != 2 {
+				t.Fatalf("Expected app and evil once each, got %+v", packages)
+			}
+			for _, pkg := range packages {
+				if pkg.Name == "evil" && (pkg.Path != installed || pkg.Integrity != "sha512-evil") {
+					t.Errorf("Expected evil at %s with its lockfile integrity, got %+v", installed, pkg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pnpmDependencySections are the importer sections listing direct dependencies.
+var pnpmDependencySections = []string{"dependencies", "devDependencies", "optionalDependencies"}
+
+// pnpmPackage is the resolution metadata of one entry of the "packages" map.
+type pnpmPackage struct {
+	name      string
+	version   string
+	integrity string
+	tarball   string
+}
+
+// parsePnpmLock parses a pnpm-lock.yaml file (lockfile v5, v6 and v9).
+// Direct dependencies are attributed to the workspace importer declaring
+// them; the remaining entries of "packages" are reported against the lockfile
+// directory.
+func parsePnpmLock(path string) ([]PackageRef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Dir(path)
+	legacy := isLegacyPnpmLock(yamlString(doc, "lockfileVersion"))
+
+	// Index the resolved packages by name@version.
+	resolved := map[string]pnpmPackage{}
+	entries := yamlMap(doc, "packages")
+	for _, key := range sortedKeys(entries) {
+		pkg := parsePnpmPackageKey(key, legacy)
+		if entry, ok := entries[key].(map[string]any); ok {
+			if name := yamlString(entry, "name"); name != "" {
+				pkg.name = name
+			}
+			if version := yamlString(entry, "version"); version != "" {
+				pkg.version = version
+			}
+			resolution := yamlMap(entry, "resolution")
+			pkg.integrity = yamlString(resolution, "integrity")
+			pkg.tarball = yamlString(resolution, "tarball")
+		}
+		if pkg.name != "" && pkg.version != "" {
+			resolved[pkg.name+"@"+pkg.version] = pkg
+		}
+	}
+
+	importers := yamlMap(doc, "importers")
+	if importers == nil {
+		// Lockfiles of single-project repositories before v6 list the
+		// dependencies at the top level.
+		importers = map[string]any{".": doc}
+	}
+
+	packages := []PackageRef{}
+	seen := map[string]bool{}
+
+	for _, importerPath := range sortedKeys(importers) {
+		importer, ok := importers[importerPath].(map[string]any)
+		if !ok {
+			continue
+		}
+		specifiers := yamlMap(importer, "specifiers")
+
+		for _, section := range pnpmDependencySections {
+			deps := yamlMap(importer, section)
+			for _, name := range sortedKeys(deps) {
+				var specifier, version string
+				switch dep := deps[name].(type) {
+				case string:
+					version, specifier = dep, yamlString(specifiers, name)
+				case map[string]any:
+					version, specifier = yamlString(dep, "version"), yamlString(dep, "specifier")
+				}
+
+				depName, depVersion, ok := parsePnpmDependencyVersion(name, version, legacy)
+				if !ok {
+					continue // link:, file: and workspace: dependencies
+				}
+
+				ref := PackageRef{
+					Name:     depName,
+					Version:  depVersion,
+					Path:     filepath.Join(root, filepath.FromSlash(importerPath)),
+					Lockfile: path,
+				}
+				if specifier != "" {
+					ref.Descriptor = name + "@" + specifier
+				}
+				if pkg, ok := resolved[depName+"@"+depVersion]; ok {
+					ref.Resolved = pkg.tarball
+					ref.Integrity = pkg.integrity
+				}
+
+				packages = append(packages, ref)
+				seen[depName+"@"+depVersion] = true
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(resolved) {
+		if seen[key] {
+			continue
+		}
+		pkg := resolved[key]
+		packages = append(packages, PackageRef{
+			Name:      pkg.name,
+			Version:   pkg.version,
+			Path:      root,
+			Lockfile:  path,
+			Resolved:  pkg.tarball,
+			Integrity: pkg.integrity,
+		})
+	}
+
+	return packages, nil
+}
+
+// isLegacyPnpmLock reports whether a lockfile predates v6, whose package keys
+// have the form "/name/version_peer@1.0.0" instead of "/name@version(peer@1.0.0)".
+func isLegacyPnpmLock(lockfileVersion string) bool {
+	v, err := strconv.ParseFloat(lockfileVersion, 64)
+	return err == nil && v < 6
+}
+
+// parsePnpmPackageKey extracts the name and version from a "packages" key:
+// "/lodash/4.17.21" (v5), "/lodash@4.17.21(peer@1.0.0)" (v6) or "lodash@4.17.21" (v9).
+func parsePnpmPackageKey(key string, legacy bool) pnpmPackage {
+	key = strings.TrimPrefix(key, "/")
+
+	if legacy {
+		// Scoped names contain a slash of their own; peer suffixes use '+' instead.
+		n := 2
+		if strings.HasPrefix(key, "@") {
+			n = 3
+		}
+		parts := strings.SplitN(key, "/", n)
+		if len(parts) != n {
+			return pnpmPackage{}
+		}
+		return pnpmPackage{
+			name:    strings.Join(parts[:n-1], "/"),
+			version: stripPnpmPeerSuffix(parts[n-1], true),
+		}
+	}
+
+	key = stripPnpmPeerSuffix(key, false)
+	at := strings.LastIndex(key, "@")
+	if at <= 0 {
+		return pnpmPackage{}
+	}
+	return pnpmPackage{name: key[:at], version: key[at+1:]}
+}
+
+// parsePnpmDependencyVersion resolves the version of an importer dependency,
+// which is either a plain version or, for aliases, a package key.
+func parsePnpmDependencyVersion(name, version string, legacy bool) (string, string, bool) {
+	if version == "" || strings.HasPrefix(version, "link:") || strings.HasPrefix(version, "file:") || strings.HasPrefix(version, "workspace:") {
+		return "", "", false
+	}
+
+	if legacy && strings.HasPrefix(version, "/") {
+		pkg := parsePnpmPackageKey(version, true)
+		return pkg.name, pkg.version, pkg.name != ""
+	}
+
+	version = stripPnpmPeerSuffix(version, legacy)
+	if !legacy && strings.Contains(strings.TrimPrefix(strings.TrimPrefix(version, "/"), "@"), "@") {
+		pkg := parsePnpmPackageKey(version, false)
+		return pkg.name, pkg.version, pkg.name != ""
+	}
+	return name, version, true
+}
+
+// stripPnpmPeerSuffix removes the peer-dependency suffix pnpm appends to
+// versions: "(react@18.2.0)" since v6, "_react@18.2.0" before.
+func stripPnpmPeerSuffix(s string, legacy bool) string {
+	if idx := strings.Index(s, "("); idx >= 0 {
+		s = s[:idx]
+	}
+	if legacy {
+		if idx := strings.Index(s, "_"); idx >= 0 {
+			s = s[:idx]
+		}
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const pnpmV5Lock = `lockfileVersion: 5.4
+
+specifiers:
+  lodash: ^4.17.0
+  react-dom: ^18.0.0
+
+dependencies:
+  lodash: 4.17.21
+  react-dom: 18.2.0_react@18.2.0
+
+packages:
+
+  /lodash/4.17.21:
+    resolution: {integrity: sha512-lodash==}
+    dev: false
+
+  /react-dom/18.2.0_react@18.2.0:
+    resolution: {integrity: sha512-reactdom==}
+    peerDependencies:
+      react: ^18.2.0
+
+  /@types/node_fetch/2.6.2_@types+react@18.0.0:
+    resolution: {integrity: sha512-types==}
+    dev: true
+`
+
+const pnpmV6Lock = `lockfileVersion: '6.0'
+
+importers:
+
+  .:
+    devDependencies:
+      typescript:
+        specifier: ^5.0.0
+        version: 5.1.6
+
+  packages/web:
+    dependencies:
+      shared:
+        specifier: workspace:*
+        version: link:../shared
+      react-dom:
+        specifier: ^18.0.0
+        version: 18.2.0(react@18.2.0)
+
+packages:
+
+  /react-dom@18.2.0(react@18.2.0):
+    resolution: {integrity: sha512-reactdom==}
+
+  /typescript@5.1.6:
+    resolution: {integrity: sha512-ts==}
+    hasBin: true
+
+  /event-stream@3.3.6:
+    resolution: {integrity: sha512-es==}
+`
+
+const pnpmV9Lock = `lockfileVersion: '9.0'
+
+settings:
+  autoInstallPeers: true
+
+importers:
+
+  .:
+    dependencies:
+      my-lodash:
+        specifier: npm:lodash@^4.17.0
+        version: lodash@4.17.21
+
+packages:
+
+  '@babel/core@7.22.0':
+    resolution: {integrity: sha512-babel==}
+
+  lodash@4.17.21:
+    resolution: {integrity: sha512-lodash==}
+
+  remote-pkg@https://example.com/remote-pkg.tgz:
+    resolution: {tarball: https://example.com/remote-pkg.tgz}
+    name: remote-pkg
+    version: 1.0.0
+
+snapshots:
+
+  '@babel/core@7.22.0(supports-color@9.0.0)':
+    dependencies:
+      lodash: 4.17.21
+`
+
+func TestParsePnpmLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []PackageRef
+	}{
+		{
+			name:    "v5 single project with peer suffixes",
+			content: pnpmV5Lock,
+			expected: []PackageRef{
+				{Name: "lodash", Version: "4.17.21", Path: ".", Integrity: "sha512-lodash==", Descriptor: "lodash@^4.17.0"},
+				{Name: "react-dom", Version: "18.2.0", Path: ".", Integrity: "sha512-reactdom==", Descriptor: "react-dom@^18.0.0"},
+				{Name: "@types/node_fetch", Version: "2.6.2", Path: ".", Integrity: "sha512-types=="},
+			},
+		},
+		{
+			name:    "v6 workspace importers",
+			content: pnpmV6Lock,
+			expected: []PackageRef{
+				{Name: "typescript", Version: "5.1.6", Path: ".", Integrity: "sha512-ts==", Descriptor: "typescript@^5.0.0"},
+				{Name: "react-dom", Version: "18.2.0", Path: "packages/web", Integrity: "sha512-reactdom==", Descriptor: "react-dom@^18.0.0"},
+				{Name: "event-stream", Version: "3.3.6", Path: ".", Integrity: "sha512-es=="},
+			},
+		},
+		{
+			name:    "v9 packages and aliases",
+			content: pnpmV9Lock,
+			expected: []PackageRef{
+				{Name: "lodash", Version: "4.17.21", Path: ".", Integrity: "sha512-lodash==", Descriptor: "my-lodash@npm:lodash@^4.17.0"},
+				{Name: "@babel/core", Version: "7.22.0", Path: ".", Integrity: "sha512-babel=="},
+				{Name: "remote-pkg", Version: "1.0.0", Path: ".", Resolved: "https://example.com/remote-pkg.tgz"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			lockPath := filepath.Join(dir, "pnpm-lock.yaml")
+			if err := os.WriteFile(lockPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write lockfile: %v", err)
+			}
+
+			packages, err := parsePnpmLock(lockPath)
+			if err != nil {
+				t.Fatalf("parsePnpmLock failed: %v", err)
+			}
+
+			if len(packages) != len(tt.expected) {
+				t.Fatalf("Expected %d packages, got %d: %+v", len(tt.expected), len(packages), packages)
+			}
+
+			for i, want := range tt.expected {
+				want.Path = filepath.Join(dir, filepath.FromSlash(want.Path))
+				want.Lockfile = lockPath
+				if !reflect.DeepEqual(packages[i], want) {
+					t.Errorf("Package %d: expected %+v, got %+v", i, want, packages[i])
+				}
+			}
+		})
+	}
+}
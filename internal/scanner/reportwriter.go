@@ -4,11 +4,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 )
 
 // ReportWriter generates reports in Pretty, JSON, and SARIF formats.
 type ReportWriter struct{}
 
+// Inventory lists what a scan found in the scanned packages besides
+// findings, suspicious or not.
+type Inventory struct {
+	Endpoints   []PackageEndpoints `json:"endpoints"`
+	Executables []BinaryFile       `json:"executables"`
+}
+
+// jsonReport is the document WriteJSONReport writes.
+type jsonReport struct {
+	Findings []Finding `json:"findings"`
+	Inventory
+}
+
 // NewReportWriter creates a new ReportWriter.
 func NewReportWriter() *ReportWriter {
 	return &ReportWriter{}
@@ -24,12 +38,36 @@ func (rw *ReportWriter) WritePretty(findings []Finding) {
 	fmt.Println("==================")
 
 	blocklistFindings := []Finding{}
+	integrityFindings := []Finding{}
+	fileHashFindings := []Finding{}
+	typosquatFindings := []Finding{}
+	obfuscationFindings := []Finding{}
+	networkFindings := []Finding{}
+	unicodeFindings := []Finding{}
+	binaryFindings := []Finding{}
+	scriptFindings := []Finding{}
 	iocFindings := []Finding{}
 
 	// Categorize findings
 	for _, finding := range findings {
 		if finding.Type == "blocklist" {
 			blocklistFindings = append(blocklistFindings, finding)
+		} else if finding.Type == "integrity" {
+			integrityFindings = append(integrityFindings, finding)
+		} else if finding.Type == "filehash" {
+			fileHashFindings = append(fileHashFindings, finding)
+		} else if finding.Type == "script" {
+			scriptFindings = append(scriptFindings, finding)
+		} else if finding.Type == "typosquat" {
+			typosquatFindings = append(typosquatFindings, finding)
+		} else if finding.Type == "obfuscation" {
+			obfuscationFindings = append(obfuscationFindings, finding)
+		} else if finding.Type == "binary" {
+			binaryFindings = append(binaryFindings, finding)
+		} else if finding.Type == "unicode" {
+			unicodeFindings = append(unicodeFindings, finding)
+		} else if finding.Type == "network" {
+			networkFindings = append(networkFindings, finding)
 		} else if finding.Type == "ioc" {
 			iocFindings = append(iocFindings, finding)
 		}
@@ -41,7 +79,155 @@ func (rw *ReportWriter) WritePretty(findings []Finding) {
 		for i, finding := range blocklistFindings {
 			fmt.Printf("%d. Package: %s@%s\n", i+1, finding.Name, finding.Version)
 			fmt.Printf("   Path: %s\n", finding.Path)
+			if finding.File != "" {
+				fmt.Printf("   Lockfile: %s\n", finding.File)
+			}
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			if finding.Advisory != "" {
+				fmt.Printf("   Advisory: %s\n", finding.Advisory)
+			}
+			if finding.Evidence != "" {
+				fmt.Printf("   Summary: %s\n", finding.Evidence)
+			}
+			if finding.Source != "" {
+				fmt.Printf("   Blocklist: %s\n", finding.Source)
+			}
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report known-malicious tarball hashes
+	if len(integrityFindings) > 0 {
+		fmt.Printf("\n🚨 KNOWN MALICIOUS TARBALLS (%d):\n", len(integrityFindings))
+		for i, finding := range integrityFindings {
+			if finding.Version != "" {
+				fmt.Printf("%d. Package: %s@%s\n", i+1, finding.Name, finding.Version)
+			} else {
+				fmt.Printf("%d. Tarball: %s\n", i+1, finding.File)
+			}
+			fmt.Printf("   Path: %s\n", finding.Path)
+			fmt.Printf("   Hash: %s\n", finding.Evidence)
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			if finding.Advisory != "" {
+				fmt.Printf("   Advisory: %s\n", finding.Advisory)
+			}
+			if finding.Source != "" {
+				fmt.Printf("   Blocklist: %s\n", finding.Source)
+			}
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report known-malicious payload files
+	if len(fileHashFindings) > 0 {
+		fmt.Printf("\n🚨 KNOWN MALICIOUS FILES (%d):\n", len(fileHashFindings))
+		for i, finding := range fileHashFindings {
+			fmt.Printf("%d. File: %s\n", i+1, finding.File)
+			fmt.Printf("   Hash: %s\n", finding.Evidence)
+			if finding.Campaign != "" {
+				fmt.Printf("   Campaign: %s\n", finding.Campaign)
+			}
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			if finding.Source != "" {
+				fmt.Printf("   Hash list: %s\n", finding.Source)
+			}
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report suspicious install-time scripts
+	if len(scriptFindings) > 0 {
+		fmt.Printf("\n🚨 SUSPICIOUS LIFECYCLE SCRIPTS (%d):\n", len(scriptFindings))
+		for i, finding := range scriptFindings {
+			fmt.Printf("%d. Package: %s@%s\n", i+1, finding.Name, finding.Version)
+			fmt.Printf("   Path: %s\n", finding.Path)
+			fmt.Printf("   Script: %s\n", finding.Evidence)
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report names imitating popular packages
+	if len(typosquatFindings) > 0 {
+		fmt.Printf("\n🔤 POSSIBLE TYPOSQUATS (%d):\n", len(typosquatFindings))
+		for i, finding := range typosquatFindings {
+			fmt.Printf("%d. Package: %s@%s\n", i+1, finding.Name, finding.Version)
+			fmt.Printf("   Path: %s\n", finding.Path)
+			fmt.Printf("   Imitates: %s\n", finding.Evidence)
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			fmt.Printf("   Confidence: %.0f%%\n", finding.Confidence*100)
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report obfuscated files
+	if len(obfuscationFindings) > 0 {
+		fmt.Printf("\n🕵️  OBFUSCATED CODE (%d):\n", len(obfuscationFindings))
+		for i, finding := range obfuscationFindings {
+			fmt.Printf("%d. File: %s:%d:%d\n", i+1, finding.File, finding.Line, finding.Column)
+			fmt.Printf("   Score: %.0f%%\n", finding.Confidence*100)
+			fmt.Printf("   Signals: %s\n", finding.Evidence)
+			writePrettySnippet(finding)
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report unexpected executables
+	if len(binaryFindings) > 0 {
+		fmt.Printf("\n⚙️  UNEXPECTED EXECUTABLES (%d):\n", len(binaryFindings))
+		for i, finding := range binaryFindings {
+			fmt.Printf("%d. File: %s\n", i+1, finding.File)
+			if finding.Name != "" {
+				fmt.Printf("   Package: %s@%s\n", finding.Name, finding.Version)
+			}
+			fmt.Printf("   Type: %s\n", finding.Description)
+			fmt.Printf("   Hash: %s\n", finding.Evidence)
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report hidden and look-alike characters
+	if len(unicodeFindings) > 0 {
+		fmt.Printf("\n🔡 HIDDEN UNICODE (%d):\n", len(unicodeFindings))
+		for i, finding := range unicodeFindings {
+			fmt.Printf("%d. File: %s:%d:%d (byte %d)\n", i+1, finding.File, finding.Line, finding.Column, finding.Offset)
+			fmt.Printf("   Found: %s\n", finding.Evidence)
 			fmt.Printf("   Reason: %s\n", finding.Reason)
+			writePrettySnippet(finding)
+			writePrettyDetails(finding)
+			fmt.Println()
+		}
+	}
+
+	// Report endpoints of exfiltration services and network IoCs
+	if len(networkFindings) > 0 {
+		fmt.Printf("\n📡 SUSPICIOUS NETWORK ENDPOINTS (%d):\n", len(networkFindings))
+		for i, finding := range networkFindings {
+			fmt.Printf("%d. File: %s:%d:%d\n", i+1, finding.File, finding.Line, finding.Column)
+			if finding.Name != "" {
+				fmt.Printf("   Package: %s@%s\n", finding.Name, finding.Version)
+			}
+			fmt.Printf("   Endpoint: %s\n", finding.Evidence)
+			if finding.Decoding != "" {
+				fmt.Printf("   Decoded from: %s\n", finding.Decoding)
+			}
+			if finding.Campaign != "" {
+				fmt.Printf("   Campaign: %s\n", finding.Campaign)
+			}
+			fmt.Printf("   Reason: %s\n", finding.Reason)
+			if finding.Source != "" {
+				fmt.Printf("   IoC list: %s\n", finding.Source)
+			}
+			writePrettySnippet(finding)
+			writePrettyDetails(finding)
 			fmt.Println()
 		}
 	}
@@ -50,14 +236,126 @@ func (rw *ReportWriter) WritePretty(findings []Finding) {
 	if len(iocFindings) > 0 {
 		fmt.Printf("\n⚠️  SUSPICIOUS CODE PATTERNS (%d):\n", len(iocFindings))
 		for i, finding := range iocFindings {
-			fmt.Printf("%d. File: %s\n", i+1, finding.File)
+			if finding.Line > 0 {
+				fmt.Printf("%d. File: %s:%d:%d (byte %d)\n", i+1, finding.File, finding.Line, finding.Column, finding.Offset)
+			} else {
+				fmt.Printf("%d. File: %s\n", i+1, finding.File)
+			}
 			fmt.Printf("   Pattern: %s\n", finding.Evidence)
+			if finding.Decoding != "" {
+				fmt.Printf("   Decoded from: %s\n", finding.Decoding)
+			}
 			fmt.Printf("   Reason: %s\n", finding.Reason)
+			if finding.RuleID != "" {
+				fmt.Printf("   Rule: %s\n", finding.RuleID)
+			}
+			if finding.Description != "" {
+				fmt.Printf("   Description: %s\n", finding.Description)
+			}
+			if finding.Count > 1 {
+				fmt.Printf("   Occurrences: %d in this file (this is #%d)\n", finding.Count, finding.Occurrence)
+			}
+			writePrettySnippet(finding)
+			writePrettyDetails(finding)
 			fmt.Println()
 		}
 	}
 }
 
+// writePrettySnippet prints the lines around a match, marking the matched line.
+func writePrettySnippet(finding Finding) {
+	if finding.Snippet == "" {
+		return
+	}
+	fmt.Println("   Context:")
+	line := snippetStart(finding.Line)
+	for _, text := range strings.Split(finding.Snippet, "\n") {
+		marker := " "
+		if line == finding.Line {
+			marker = ">"
+		}
+		fmt.Printf("   %s %5d | %s\n", marker, line, text)
+		line++
+	}
+}
+
+// writePrettyDetails prints the severity, references and remediation of a finding.
+func writePrettyDetails(finding Finding) {
+	if finding.Severity != "" {
+		fmt.Printf("   Severity: %s\n", strings.ToUpper(finding.Severity))
+	}
+	for _, ref := range finding.References {
+		fmt.Printf("   Reference: %s\n", ref)
+	}
+	if finding.Remediation != "" {
+		fmt.Printf("   Remediation: %s\n", finding.Remediation)
+	}
+}
+
+// WriteEndpoints writes the network endpoints named by each package,
+// marking those of exfiltration services and network IoCs.
+func (rw *ReportWriter) WriteEndpoints(packages []PackageEndpoints) {
+	if len(packages) == 0 {
+		return
+	}
+
+	fmt.Printf("\n🌐 NETWORK ENDPOINTS (%d packages):\n", len(packages))
+	for _, pkg := range packages {
+		if pkg.Name != "" {
+			fmt.Printf("\n%s@%s (%s):\n", pkg.Name, pkg.Version, pkg.Path)
+		} else {
+			fmt.Printf("\n%s:\n", pkg.Path)
+		}
+		for _, endpoint := range pkg.Endpoints {
+			notes := []string{}
+			if endpoint.IoC {
+				notes = append(notes, "KNOWN MALICIOUS")
+			}
+			if endpoint.Service != "" {
+				notes = append(notes, endpoint.Service)
+			}
+			if endpoint.Decoding != "" {
+				notes = append(notes, "decoded from "+endpoint.Decoding)
+			}
+			if endpoint.Count > 1 {
+				notes = append(notes, fmt.Sprintf("%d times", endpoint.Count))
+			}
+			note := ""
+			if len(notes) > 0 {
+				note = " [" + strings.Join(notes, ", ") + "]"
+			}
+			fmt.Printf("   %-4s %s%s\n", endpoint.Kind, endpoint.Value, note)
+			fmt.Printf("        at %s:%d:%d\n", endpoint.File, endpoint.Line, endpoint.Column)
+		}
+	}
+}
+
+// WriteBinaries writes the executables found in the scanned packages,
+// marking those reported as unexpected.
+func (rw *ReportWriter) WriteBinaries(files []BinaryFile) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Printf("\n🧩 EXECUTABLES (%d):\n", len(files))
+	for _, file := range files {
+		kind := file.Format
+		if file.Arch != "" {
+			kind += " " + file.Arch
+		}
+		flag := ""
+		if file.Flagged {
+			flag = " [UNEXPECTED]"
+		}
+		fmt.Printf("   %s (%s, %d bytes)%s\n", file.File, kind, file.Size, flag)
+		if file.Name != "" {
+			fmt.Printf("        package %s@%s, sha256:%s\n", file.Name, file.Version, file.SHA256)
+		} else {
+			fmt.Printf("        sha256:%s\n", file.SHA256)
+		}
+	}
+}
+
 // WriteJSON writes a JSON report.
 func (rw *ReportWriter) WriteJSON(findings []Finding, outputPath string) error {
 	file, err := os.Create(outputPath)
@@ -69,8 +367,33 @@ func (rw *ReportWriter) WriteJSON(findings []Finding, outputPath string) error {
 	return json.NewEncoder(file).Encode(findings)
 }
 
-// WriteSARIF writes a SARIF report.
+// WriteJSONReport writes a JSON report of the findings together with the
+// inventory of the scanned packages.
+func (rw *ReportWriter) WriteJSONReport(findings []Finding, inventory Inventory, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(jsonReport{Findings: findings, Inventory: inventory})
+}
+
+// WriteSARIF writes a SARIF 2.1.0 report.
 func (rw *ReportWriter) WriteSARIF(findings []Finding, outputPath string) error {
-	// Placeholder for SARIF generation logic
-	return fmt.Errorf("SARIF generation not implemented")
+	return rw.WriteSARIFReport(findings, Inventory{}, outputPath)
+}
+
+// WriteSARIFReport writes a SARIF 2.1.0 report with the inventory of the
+// scanned packages in the properties of the run.
+func (rw *ReportWriter) WriteSARIFReport(findings []Finding, inventory Inventory, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(buildSARIF(findings, inventory))
 }
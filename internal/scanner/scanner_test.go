@@ -50,3 +50,163 @@ func TestDependencyReader(t *testing.T) {
 		t.Errorf("Unexpected package data: %+v", packages[0])
 	}
 }
+
+func TestDiscoverer_NodeModules(t *testing.T) {
+	discoverer, err := NewDiscoverer(nil)
+	if err != nil {
+		t.Fatalf("Failed to create discoverer: %v", err)
+	}
+
+	testDir := t.TempDir()
+	project := filepath.Join(testDir, "project")
+	global := filepath.Join(testDir, "global", "node_modules")
+	for _, dir := range []string{
+		filepath.Join(project, "node_modules", "lodash"),
+		filepath.Join(global, "npm"),
+	} {
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "package.json"), []byte("{}"), 0644)
+	}
+	os.WriteFile(filepath.Join(project, "package.json"), []byte("{}"), 0644)
+
+	targets, err := discoverer.Discover([]string{testDir})
+	if err != nil {
+		t.Fatalf("Failed to discover targets: %v", err)
+	}
+
+	// The project owns its node_modules; the global root has no owner.
+	if len(targets) != 2 || targets[0].Path != global || targets[1].Path != project {
+		t.Errorf("Expected targets [%s %s], got %+v", global, project, targets)
+	}
+
+	targets, err = discoverer.Discover([]string{filepath.Join(project, "node_modules")})
+	if err != nil {
+		t.Fatalf("Failed to discover targets: %v", err)
+	}
+	if len(targets) != 1 {
+		t.Errorf("Expected a node_modules scan root to be a target, got %+v", targets)
+	}
+}
+
+func TestDependencyReader_InstalledTree(t *testing.T) {
+	testDir := t.TempDir()
+
+	installed := map[string]string{
+		"package.json":                                           `{"name": "app", "version": "1.0.0"}`,
+		"node_modules/lodash/package.json":                       `{"name": "lodash", "version": "4.17.21"}`,
+		"node_modules/lodash/test/fixture/package.json":          `{"name": "fixture", "version": "0.0.0"}`,
+		"node_modules/@scope/pkg/package.json":                   `{"name": "@scope/pkg", "version": "2.0.0"}`,
+		"node_modules/a/package.json":                            `{"name": "a", "version": "1.0.0"}`,
+		"node_modules/a/node_modules/b/package.json":             `{"name": "b", "version": "2.0.0"}`,
+		"node_modules/.bin/placeholder":                          ``,
+		"node_modules/.pnpm/c@3.0.0/node_modules/c/package.json": `{"name": "c", "version": "3.0.0"}`,
+	}
+	for file, content := range installed {
+		full := filepath.Join(testDir, filepath.FromSlash(file))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+
+	// pnpm links direct dependencies into the virtual store.
+	store := filepath.Join(testDir, "node_modules", ".pnpm", "c@3.0.0", "node_modules", "c")
+	symlinked := os.Symlink(store, filepath.Join(testDir, "node_modules", "c")) == nil
+
+	expected := map[string]string{
+		"app":        "1.0.0",
+		"lodash":     "4.17.21",
+		"@scope/pkg": "2.0.0",
+		"a":          "1.0.0",
+		"b":          "2.0.0",
+		"c":          "3.0.0",
+	}
+
+	for _, root := range []string{testDir, filepath.Join(testDir, "node_modules")} {
+		packages, err := NewDependencyReader().ReadDependencies(root)
+		if err != nil {
+			t.Fatalf("Failed to read dependencies: %v", err)
+		}
+
+		got := map[string]string{}
+		for _, pkg := range packages {
+			if _, dup := got[pkg.Name]; dup {
+				t.Errorf("Package %s reported more than once (symlinked=%v)", pkg.Name, symlinked)
+			}
+			got[pkg.Name] = pkg.Version
+		}
+
+		for name, version := range expected {
+			if name == "app" && root != testDir {
+				continue
+			}
+			if got[name] != version {
+				t.Errorf("Scanning %s: expected %s@%s, got %+v", root, name, version, got)
+			}
+		}
+		if _, ok := got["fixture"]; ok {
+			t.Errorf("Scanning %s: package.json files inside installed packages should be ignored", root)
+		}
+	}
+}
+
+func TestNestedTargets(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "packages", "a")
+	evil := filepath.Join(workspace, "node_modules", "evil")
+	os.MkdirAll(filepath.Join(evil, "bin"), 0755)
+	os.WriteFile(filepath.Join(root, "package.json"), []byte(`{"name": "monorepo", "version": "1.0.0"}`), 0644)
+	os.WriteFile(filepath.Join(workspace, "package.json"), []byte(`{"name": "a", "version": "1.0.0"}`), 0644)
+	os.WriteFile(filepath.Join(evil, "package.json"), []byte(`{"name": "evil", "version": "1.0.0"}`), 0644)
+	os.WriteFile(filepath.Join(evil, "bin", "update"), elfHeader(0x3e), 0755)
+
+	discoverer, _ := NewDiscoverer(nil)
+	targets, err := discoverer.Discover([]string{root})
+	if err != nil || len(targets) != 2 {
+		t.Fatalf("Expected the root and the workspace as targets, got %+v (err %v)", targets, err)
+	}
+
+	s, err := NewIoCScanner(nil, 10)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	s.SkipNestedTargets = true
+	s.Binaries = NewBinaryDetector()
+
+	// Each package and file belongs to exactly one target.
+	packages := map[string][]string{}
+	binaries := map[string][]string{}
+	for _, target := range targets {
+		refs, err := NewDependencyReader().ReadDependencies(target.Path)
+		if err != nil {
+			t.Fatalf("Failed to read dependencies: %v", err)
+		}
+		for _, pkg := range refs {
+			packages[pkg.Name] = append(packages[pkg.Name], target.Path)
+		}
+		findings, err := s.Scan(target.Path)
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		for _, f := range findings {
+			binaries[f.File] = append(binaries[f.File], target.Path)
+		}
+	}
+
+	if got := packages["evil"]; len(got) != 1 || got[0] != workspace {
+		t.Errorf("Expected evil to be read from %s only, got %v", workspace, got)
+	}
+	if got := binaries[filepath.Join(evil, "bin", "update")]; len(got) != 1 || got[0] != workspace {
+		t.Errorf("Expected the executable to be reported from %s only, got %v", workspace, got)
+	}
+	if len(s.Binaries.Files()) != 1 {
+		t.Errorf("Expected one executable in the inventory, got %+v", s.Binaries.Files())
+	}
+
+	// Installed packages are not nested targets when the target is "."
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	os.Chdir(workspace)
+	findings, err := s.Scan(".")
+	if err != nil || len(findings) != 1 || findings[0].File != filepath.Join("node_modules", "evil", "bin", "update") {
+		t.Errorf("Expected the executable when scanning the workspace as ., got %+v (err %v)", findings, err)
+	}
+}
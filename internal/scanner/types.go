@@ -1,11 +1,58 @@
 package scanner
 
+import "strings"
+
+// Severity levels assigned to findings, from most to least urgent.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// severityRanks orders the Severity constants, the most urgent highest.
+var severityRanks = map[string]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3, SeverityCritical: 4}
+
 type Finding struct {
-	Type     string
-	Name     string
-	Version  string
-	Path     string
-	File     string
-	Reason   string
-	Evidence string
+	Type        string
+	Name        string
+	Version     string
+	Path        string
+	File        string
+	Reason      string
+	Description string
+	Evidence    string
+	Pattern     string
+	Line        int
+	Column      int
+	Offset      int
+	Length      int // Bytes of File located at Offset, when Evidence is not them
+	Snippet     string
+	Decoding    string // Encodings, outermost first, hiding the match, e.g. "base64 > charcode"
+	Count       int    // Matches of the rule in File
+	Occurrence  int    // 1-based index of this match among them
+	Advisory    string
+	Severity    string
+	RuleID      string
+	References  []string
+	Remediation string
+	Source      string
+	Campaign    string
+	Confidence  float64
+}
+
+// normalizeSeverity maps a severity label (e.g. "HIGH" or "moderate") to one
+// of the Severity constants, or returns fallback when it is not recognized.
+func normalizeSeverity(severity, fallback string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return SeverityCritical
+	case "high":
+		return SeverityHigh
+	case "medium", "moderate":
+		return SeverityMedium
+	case "low":
+		return SeverityLow
+	}
+	return fallback
 }
@@ -0,0 +1,260 @@
+package scanner
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+//go:embed data/popular-packages.txt
+var popularPackagesList string
+
+//go:embed data/typosquat-allowlist.txt
+var typosquatAllowlist string
+
+// PopularPackages returns the bundled list of popular npm package names.
+func PopularPackages() []string {
+	return packageNameList(popularPackagesList)
+}
+
+// TyposquatAllowlist returns the bundled list of legitimate package names
+// that resemble a popular one.
+func TyposquatAllowlist() []string {
+	return packageNameList(typosquatAllowlist)
+}
+
+// packageNameList parses a bundled list of package names, one per line.
+func packageNameList(list string) []string {
+	names := []string{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names
+}
+
+// Typosquatting techniques named in findings.
+const (
+	techniqueHomoglyph = "homoglyph"
+	techniqueScope     = "scope confusion"
+	techniqueSeparator = "separator swap"
+	techniqueAffix     = "affix"
+	techniqueEdit      = "edit distance"
+)
+
+// typosquatAffixes are decorations commonly added to a popular name.
+var typosquatAffixes = []struct{ prefix, suffix string }{
+	{suffix: ".js"},
+	{suffix: "-js"},
+	{suffix: "js"},
+	{prefix: "node-"},
+}
+
+// homoglyphs maps Cyrillic and Greek letters to the Latin letter they render
+// like. ASCII characters are left alone: the names they would make equal are
+// too often distinct, legitimate packages.
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ο': 'o', 'ν': 'v', 'α': 'a',
+}
+
+// homoglyphSequences are letter pairs that render like a single letter. They
+// are only replaced in the name being checked, never in the popular name.
+var homoglyphSequences = strings.NewReplacer("rn", "m", "vv", "w", "cl", "d")
+
+// TyposquatDetector flags package names that imitate popular packages. A
+// name is only reported when it is less popular than the name it resembles:
+// names on the popular list or the allowlist are never reported.
+type TyposquatDetector struct {
+	Popular []string
+
+	// Allowlist holds legitimate names that resemble a popular one.
+	Allowlist []string
+
+	// MinConfidence drops matches scoring below it (0 to 1).
+	MinConfidence float64
+
+	normalized []string
+	known      map[string]bool
+}
+
+// NewTyposquatDetector creates a TyposquatDetector comparing names against
+// popular, with the bundled allowlist.
+func NewTyposquatDetector(popular []string) *TyposquatDetector {
+	d := &TyposquatDetector{Popular: popular, Allowlist: TyposquatAllowlist()}
+	d.buildIndex()
+	return d
+}
+
+// buildIndex normalizes the popular and allowed names once for all
+// comparisons.
+func (d *TyposquatDetector) buildIndex() {
+	d.normalized = make([]string, 0, len(d.Popular))
+	d.known = make(map[string]bool, len(d.Popular)+len(d.Allowlist))
+	for _, name := range d.Popular {
+		name = normalizePackageName(name)
+		d.normalized = append(d.normalized, name)
+		d.known[name] = true
+	}
+	for _, name := range d.Allowlist {
+		d.known[normalizePackageName(name)] = true
+	}
+}
+
+// typosquatMatch is the best imitation found for a name.
+type typosquatMatch struct {
+	imitates   string
+	technique  string
+	confidence float64
+}
+
+// Check reports pkg when its name imitates a popular package. Only the most
+// likely imitated package is reported.
+func (d *TyposquatDetector) Check(pkg PackageRef) []Finding {
+	findings := []Finding{}
+
+	m, ok := d.match(pkg.Name)
+	if !ok || m.confidence < d.MinConfidence {
+		return findings
+	}
+
+	severity := SeverityMedium
+	if m.confidence >= 0.8 {
+		severity = SeverityHigh
+	} else if m.confidence < 0.6 {
+		severity = SeverityLow
+	}
+
+	return append(findings, Finding{
+		Type:        "typosquat",
+		Name:        pkg.Name,
+		Version:     pkg.Version,
+		Path:        pkg.Path,
+		File:        pkg.Lockfile,
+		Reason:      fmt.Sprintf("Name imitates popular package %s (%s)", m.imitates, m.technique),
+		Evidence:    m.imitates,
+		Confidence:  m.confidence,
+		Severity:    severity,
+		RuleID:      "typosquat/" + strings.ReplaceAll(m.technique, " ", "-"),
+		Remediation: fmt.Sprintf("Check that %s is the intended dependency and not a misspelling of %s", pkg.Name, m.imitates),
+	})
+}
+
+// match compares name against every popular package and returns the
+// imitation with the highest confidence.
+func (d *TyposquatDetector) match(name string) (typosquatMatch, bool) {
+	if d.known == nil {
+		d.buildIndex()
+	}
+
+	name = normalizePackageName(name)
+	if name == "" || d.known[name] {
+		return typosquatMatch{}, false
+	}
+
+	best := typosquatMatch{}
+	for _, popular := range d.normalized {
+		technique, confidence := typosquatTechnique(name, popular)
+		if confidence > best.confidence {
+			best = typosquatMatch{imitates: popular, technique: technique, confidence: confidence}
+		}
+	}
+	return best, best.confidence > 0
+}
+
+// typosquatTechnique returns how name imitates popular, if it does.
+func typosquatTechnique(name, popular string) (string, float64) {
+	if skeleton := homoglyphSkeleton(name); skeleton == popular || homoglyphSequences.Replace(skeleton) == popular {
+		return techniqueHomoglyph, 0.9
+	}
+
+	if stripSeparators(name) == stripSeparators(popular) {
+		if strings.HasPrefix(name, "@") != strings.HasPrefix(popular, "@") || strings.Contains(name, "/") != strings.Contains(popular, "/") {
+			return techniqueScope, 0.85
+		}
+		return techniqueSeparator, 0.85
+	}
+
+	for _, affix := range typosquatAffixes {
+		if name == affix.prefix+popular+affix.suffix {
+			return techniqueAffix, 0.7
+		}
+	}
+
+	// Short names are a single edit away from too many legitimate packages.
+	if len(popular) < 5 || abs(len(name)-len(popular)) > 2 {
+		return "", 0
+	}
+	switch distance := editDistance(name, popular); {
+	case distance == 1 && len(popular) >= 7:
+		return techniqueEdit, 0.75
+	case distance == 1:
+		return techniqueEdit, 0.6
+	case distance == 2 && len(popular) >= 9:
+		return techniqueEdit, 0.5
+	}
+	return "", 0
+}
+
+// homoglyphSkeleton maps look-alike letters of a name to the Latin letters
+// they render like.
+func homoglyphSkeleton(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if c, ok := homoglyphs[r]; ok {
+			r = c
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// stripSeparators removes the scope marker and the separators npm allows in
+// names, so "cross-env", "crossenv" and "@cross/env" compare equal.
+func stripSeparators(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '@', '/', '-', '_', '.':
+			return -1
+		}
+		return r
+	}, name)
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: insertions, deletions, substitutions and adjacent transpositions.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
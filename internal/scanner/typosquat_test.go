@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTyposquatDetector_Check(t *testing.T) {
+	detector := NewTyposquatDetector(PopularPackages())
+
+	tests := []struct {
+		name              string
+		pkg               string
+		expectedImitates  string
+		expectedTechnique string
+	}{
+		{"separator removed", "crossenv", "cross-env", techniqueSeparator},
+		{"separator swapped", "discordjs", "discord.js", techniqueSeparator},
+		{"missing letter", "mongose", "mongoose", techniqueEdit},
+		{"transposed letters", "reqeust", "request", techniqueEdit},
+		{"scope flattened", "babelcli", "@babel/cli", techniqueScope},
+		{"scope separator swapped", "@types-node", "@types/node", techniqueScope},
+		{"js suffix", "d3.js", "d3", techniqueAffix},
+		{"digit for letter", "l0dash", "lodash", techniqueEdit},
+		{"letter pair homoglyph", "rnongoose", "mongoose", techniqueHomoglyph},
+		{"cyrillic homoglyph", "еxpress", "express", techniqueHomoglyph},
+		{"cyrillic homoglyph with letter pair", "clаssnames", "classnames", techniqueHomoglyph},
+		{"letter pair in popular name", "trough", "", ""},
+		{"more popular name", "color", "", ""},
+		{"similar legitimate package", "lodash.set", "", ""},
+		{"popular package", "cross-env", "", ""},
+		{"popular package with different case", "Lodash", "", ""},
+		{"short name one edit away", "was", "", ""},
+		{"unrelated name", "my-internal-tool", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := detector.Check(PackageRef{Name: tt.pkg, Version: "1.0.0", Path: "/app", Lockfile: "/app/package-lock.json"})
+			if tt.expectedImitates == "" {
+				if len(findings) != 0 {
+					t.Errorf("Expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("Expected 1 finding, got %d", len(findings))
+			}
+
+			f := findings[0]
+			if f.Type != "typosquat" || f.Name != tt.pkg || f.File != "/app/package-lock.json" {
+				t.Errorf("Unexpected finding: %+v", f)
+			}
+			if f.Evidence != tt.expectedImitates {
+				t.Errorf("Expected imitated package %s, got %s", tt.expectedImitates, f.Evidence)
+			}
+			if f.RuleID != "typosquat/"+strings.ReplaceAll(tt.expectedTechnique, " ", "-") {
+				t.Errorf("Expected technique %s, got rule %s", tt.expectedTechnique, f.RuleID)
+			}
+			if f.Confidence <= 0 || f.Confidence > 1 {
+				t.Errorf("Expected confidence in (0, 1], got %v", f.Confidence)
+			}
+		})
+	}
+}
+
+func TestTyposquatDetector_MinConfidence(t *testing.T) {
+	detector := NewTyposquatDetector([]string{"lodash", "mongoose"})
+	detector.MinConfidence = 0.8
+
+	if findings := detector.Check(PackageRef{Name: "mongose"}); len(findings) != 0 {
+		t.Errorf("Expected edit-distance match below threshold to be dropped, got %+v", findings)
+	}
+	if findings := detector.Check(PackageRef{Name: "rnongoose"}); len(findings) != 1 || findings[0].Severity != SeverityHigh {
+		t.Errorf("Expected 1 high severity finding, got %+v", findings)
+	}
+}
+
+func TestTyposquatDetector_Allowlist(t *testing.T) {
+	detector := &TyposquatDetector{Popular: []string{"lodash.get"}, Allowlist: []string{"lodash.gets"}}
+
+	if findings := detector.Check(PackageRef{Name: "lodash.gets"}); len(findings) != 0 {
+		t.Errorf("Expected allowed name not to be reported, got %+v", findings)
+	}
+	if findings := detector.Check(PackageRef{Name: "lodash.gett"}); len(findings) != 1 {
+		t.Errorf("Expected 1 finding, got %+v", findings)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"lodash", "lodash", 0},
+		{"lodash", "lodsah", 1},
+		{"mongoose", "mongose", 1},
+		{"express", "expresss", 1},
+		{"webpack", "diwebpack", 2},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b); got != tt.expected {
+				t.Errorf("Expected distance %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
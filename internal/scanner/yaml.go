@@ -0,0 +1,377 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML written by package managers into nested
+// map[string]any, []any and string values. It supports block mappings and
+// sequences, plain and quoted scalars, flow collections and comments; anchors,
+// tags and multi-line scalars are not supported.
+func parseYAML(data []byte) (map[string]any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.TrimSpace(trimmed) == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{
+			num:    i + 1,
+			indent: len(text) - len(trimmed),
+			text:   strings.TrimRight(trimmed, " \t"),
+		})
+	}
+
+	if len(p.lines) == 0 {
+		return map[string]any{}, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml: line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+
+	root, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("yaml: document is not a mapping")
+	}
+	return root, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock parses the mapping or sequence whose entries start at indent.
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml: line %d: unexpected indentation", line.num)
+		}
+
+		key, rest, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %v", line.num, err)
+		}
+		p.pos++
+
+		value, err := p.parseValue(rest, indent, line.num)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+
+	return m, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	seq := []any{}
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || !isYAMLSequenceItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml: line %d: unexpected indentation", line.num)
+		}
+
+		item := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if item == "" {
+			p.pos++
+			value, err := p.parseValue("", indent, line.num)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, value)
+			continue
+		}
+
+		if _, _, err := splitYAMLKey(item); err == nil && !strings.HasPrefix(item, "{") && !strings.HasPrefix(item, "[") {
+			// "- key: value" starts a mapping indented past the dash.
+			itemIndent := line.indent + len(line.text) - len(item)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: itemIndent, text: item}
+			value, err := p.parseMapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, value)
+			continue
+		}
+
+		p.pos++
+		value, err := parseYAMLScalar(item)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %v", line.num, err)
+		}
+		seq = append(seq, value)
+	}
+
+	return seq, nil
+}
+
+// parseValue parses the value following a key: either inline text or a
+// nested block on the following, more indented lines.
+func (p *yamlParser) parseValue(rest string, indent, num int) (any, error) {
+	if rest != "" {
+		value, err := parseYAMLScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %v", num, err)
+		}
+		return value, nil
+	}
+
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		// Block sequences may sit at the same indentation as their key.
+		if next.indent > indent || (next.indent == indent && isYAMLSequenceItem(next.text)) {
+			return p.parseBlock(next.indent)
+		}
+	}
+	return "", nil
+}
+
+// splitYAMLKey splits "key: value" into its unquoted key and raw value.
+func splitYAMLKey(text string) (string, string, error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text, 0)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+		after := text[end+1:]
+		if !strings.HasPrefix(after, ":") {
+			return "", "", fmt.Errorf("expected ':' after key")
+		}
+		key, err := unquoteYAML(text[:end+1])
+		if err != nil {
+			return "", "", err
+		}
+		return key, strings.TrimSpace(after[1:]), nil
+	}
+
+	if idx := strings.Index(text, ": "); idx >= 0 {
+		return strings.TrimSpace(text[:idx]), strings.TrimSpace(text[idx+2:]), nil
+	}
+	if strings.HasSuffix(text, ":") {
+		return strings.TrimSpace(text[:len(text)-1]), "", nil
+	}
+	return "", "", fmt.Errorf("expected 'key: value'")
+}
+
+// parseYAMLScalar parses an inline value: a quoted or plain scalar or a flow collection.
+func parseYAMLScalar(text string) (any, error) {
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+		value, rest, err := parseYAMLFlow(text)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("unexpected %q after flow collection", rest)
+		}
+		return value, nil
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		return unquoteYAML(text)
+	}
+	return text, nil
+}
+
+// parseYAMLFlow parses a flow collection or scalar at the start of text and
+// returns the unconsumed remainder.
+func parseYAMLFlow(text string) (any, string, error) {
+	text = strings.TrimLeft(text, " ")
+	if text == "" {
+		return nil, "", fmt.Errorf("unexpected end of flow collection")
+	}
+
+	switch text[0] {
+	case '{':
+		m := map[string]any{}
+		rest := strings.TrimLeft(text[1:], " ")
+		for !strings.HasPrefix(rest, "}") {
+			keyValue, after, err := parseYAMLFlow(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			key, ok := keyValue.(string)
+			if !ok {
+				return nil, "", fmt.Errorf("flow mapping key is not a scalar")
+			}
+			after = strings.TrimLeft(after, " ")
+			if !strings.HasPrefix(after, ":") {
+				return nil, "", fmt.Errorf("expected ':' in flow mapping")
+			}
+			value, after, err := parseYAMLFlow(after[1:])
+			if err != nil {
+				return nil, "", err
+			}
+			m[key] = value
+			rest, err = flowSeparator(after, '}')
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		return m, rest[1:], nil
+
+	case '[':
+		seq := []any{}
+		rest := strings.TrimLeft(text[1:], " ")
+		for !strings.HasPrefix(rest, "]") {
+			value, after, err := parseYAMLFlow(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			seq = append(seq, value)
+			rest, err = flowSeparator(after, ']')
+			if err != nil {
+				return nil, "", err
+			}
+		}
+		return seq, rest[1:], nil
+
+	case '"', '\'':
+		end := closingQuote(text, 0)
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated quoted scalar")
+		}
+		value, err := unquoteYAML(text[:end+1])
+		return value, text[end+1:], err
+	}
+
+	// Plain scalar inside a flow collection ends at ',', ']', '}' or ': '.
+	end := len(text)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == ',' || c == ']' || c == '}' || (c == ':' && (i+1 == len(text) || text[i+1] == ' ')) {
+			end = i
+			break
+		}
+	}
+	return strings.TrimSpace(text[:end]), text[end:], nil
+}
+
+// flowSeparator consumes the ',' between flow items and stops before the closing bracket.
+func flowSeparator(text string, closing byte) (string, error) {
+	text = strings.TrimLeft(text, " ")
+	if strings.HasPrefix(text, ",") {
+		return strings.TrimLeft(text[1:], " "), nil
+	}
+	if text == "" || text[0] != closing {
+		return "", fmt.Errorf("expected ',' or %q in flow collection", closing)
+	}
+	return text, nil
+}
+
+// closingQuote returns the index of the quote closing the one at start, or -1.
+func closingQuote(text string, start int) int {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteYAML removes the quotes around a scalar and resolves its escapes.
+func unquoteYAML(text string) (string, error) {
+	if len(text) < 2 || text[len(text)-1] != text[0] {
+		return "", fmt.Errorf("malformed quoted scalar %s", text)
+	}
+	body := text[1 : len(text)-1]
+	if text[0] == '\'' {
+		return strings.ReplaceAll(body, "''", "'"), nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' || i+1 == len(body) {
+			b.WriteByte(body[i])
+			continue
+		}
+		i++
+		switch body[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// stripYAMLComment removes a trailing "# comment" that is not inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '[' || line[i-1] == '{' || line[i-1] == ',' || line[i-1] == ':' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// isYAMLSequenceItem reports whether a line is a block sequence entry.
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlString returns m[key] if it is a scalar.
+func yamlString(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// yamlMap returns m[key] if it is a mapping.
+func yamlMap(m map[string]any, key string) map[string]any {
+	child, _ := m[key].(map[string]any)
+	return child
+}
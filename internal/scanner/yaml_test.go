@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	input := `# comment
+lockfileVersion: '6.0'
+
+importers:
+  .:
+    dependencies:
+      lodash:
+        specifier: ^4.17.0
+        version: 4.17.21
+
+packages:
+  /lodash@4.17.21:
+    resolution: {integrity: sha512-abc==, tarball: "https://example.com/x.tgz"}
+    cpu: [x64, arm64]
+    os:
+    - darwin
+    - linux
+  "quoted key: with colon": 'it''s' # trailing comment
+list:
+  - name: first
+    value: 1
+  - second
+`
+
+	expected := map[string]any{
+		"lockfileVersion": "6.0",
+		"importers": map[string]any{
+			".": map[string]any{
+				"dependencies": map[string]any{
+					"lodash": map[string]any{"specifier": "^4.17.0", "version": "4.17.21"},
+				},
+			},
+		},
+		"packages": map[string]any{
+			"/lodash@4.17.21": map[string]any{
+				"resolution": map[string]any{"integrity": "sha512-abc==", "tarball": "https://example.com/x.tgz"},
+				"cpu":        []any{"x64", "arm64"},
+				"os":         []any{"darwin", "linux"},
+			},
+			"quoted key: with colon": "it's",
+		},
+		"list": []any{
+			map[string]any{"name": "first", "value": "1"},
+			"second",
+		},
+	}
+
+	got, err := parseYAML([]byte(input))
+	if err != nil {
+		t.Fatalf("parseYAML failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected result:\n got: %#v\nwant: %#v", got, expected)
+	}
+
+	invalid := []string{
+		"key: value\n    over: indented\n",
+		"\"unterminated: x\n",
+		"key: {a: b\n",
+		"- just\n- a list\n",
+	}
+	for _, doc := range invalid {
+		if _, err := parseYAML([]byte(doc)); err == nil {
+			t.Errorf("Expected error for %q, got nil", doc)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseYarnLock parses a yarn.lock file in either the classic v1 format or
+// the YAML format written by Yarn 2+ (Berry).
+func parseYarnLock(path string) ([]PackageRef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBerryLockfile(data) {
+		return parseBerryLock(data, path)
+	}
+	return parseYarnV1Lock(data, path), nil
+}
+
+// isBerryLockfile reports whether a yarn.lock was written by Yarn 2+, which
+// always records a top-level __metadata entry.
+func isBerryLockfile(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("__metadata:")) || bytes.Contains(data, []byte("\n__metadata:"))
+}
+
+// parseYarnV1Lock parses the classic Yarn lockfile format:
+//
+//	"lodash@^4.17.0", lodash@^4.17.20:
+//	  version "4.17.21"
+//	  resolved "https://registry.yarnpkg.com/lodash/-/lodash-4.17.21.tgz#..."
+//	  integrity sha512-...
+func parseYarnV1Lock(data []byte, lockfile string) []PackageRef {
+	packages := []PackageRef{}
+	var current *PackageRef
+
+	flush := func() {
+		if current != nil && current.Version != "" {
+			packages = append(packages, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := len(line) - len(trimmed)
+		if indent == 0 {
+			flush()
+			descriptors := splitDescriptors(strings.TrimSuffix(trimmed, ":"))
+			if len(descriptors) == 0 {
+				continue
+			}
+			current = &PackageRef{
+				Name:       descriptorName(descriptors[0]),
+				Path:       filepath.Dir(lockfile),
+				Lockfile:   lockfile,
+				Descriptor: strings.Join(descriptors, ", "),
+			}
+			continue
+		}
+
+		if current == nil || indent != 2 {
+			continue // Nested dependency lists
+		}
+
+		key, value, _ := strings.Cut(trimmed, " ")
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "version":
+			current.Version = value
+		case "resolved":
+			current.Resolved = value
+		case "integrity":
+			current.Integrity = value
+		}
+	}
+	flush()
+
+	return packages
+}
+
+// parseBerryLock parses the YAML lockfile written by Yarn 2+.
+func parseBerryLock(data []byte, lockfile string) ([]PackageRef, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := []PackageRef{}
+	for _, key := range sortedKeys(doc) {
+		entry, ok := doc[key].(map[string]any)
+		if !ok || key == "__metadata" || yamlString(entry, "linkType") == "soft" {
+			continue // Metadata, workspaces and portals
+		}
+
+		descriptors := splitDescriptors(key)
+		resolution := yamlString(entry, "resolution")
+		name := descriptorName(resolution)
+		if name == "" && len(descriptors) > 0 {
+			name = descriptorName(descriptors[0])
+		}
+
+		packages = append(packages, PackageRef{
+			Name:       name,
+			Version:    yamlString(entry, "version"),
+			Path:       filepath.Dir(lockfile),
+			Lockfile:   lockfile,
+			Resolved:   resolution,
+			Descriptor: strings.Join(descriptors, ", "),
+		})
+	}
+
+	return packages, nil
+}
+
+// splitDescriptors splits a lockfile entry key such as
+// `"lodash@^4.17.0", lodash@^4.17.20` into its individual descriptors.
+func splitDescriptors(key string) []string {
+	descriptors := []string{}
+	for _, part := range strings.Split(key, ",") {
+		part = strings.Trim(strings.TrimSpace(part), `"`)
+		if part != "" {
+			descriptors = append(descriptors, part)
+		}
+	}
+	return descriptors
+}
+
+// descriptorName returns the package a descriptor resolves to, following
+// npm aliases: "@scope/pkg@^1.0.0" is @scope/pkg, "alias@npm:real@^1.0.0" is real.
+func descriptorName(descriptor string) string {
+	at := strings.Index(strings.TrimPrefix(descriptor, "@"), "@")
+	if at < 0 {
+		return descriptor
+	}
+	if strings.HasPrefix(descriptor, "@") {
+		at++
+	}
+
+	name, rng := descriptor[:at], descriptor[at+1:]
+	if spec, ok := strings.CutPrefix(rng, "npm:"); ok {
+		if alias := strings.Index(strings.TrimPrefix(spec, "@"), "@"); alias >= 0 {
+			if strings.HasPrefix(spec, "@") {
+				alias++
+			}
+			return spec[:alias]
+		}
+	}
+	return name
+}
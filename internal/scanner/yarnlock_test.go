@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const yarnV1Lock = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+  version "7.12.13"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.12.13.tgz#dcfc826b"
+  integrity sha512-HV1Cm0Q3ZrpCR93tkWOYiuYIgLxZXZFVG2VgK+MBWjUqZTundupbfx2aXarXuw5Ko5aMcjtJgbSs4vUGBS5v6g==
+  dependencies:
+    "@babel/highlight" "^7.12.13"
+
+lodash@^4.17.20:
+  version "4.17.21"
+  resolved "https://registry.yarnpkg.com/lodash/-/lodash-4.17.21.tgz#679591c5"
+  integrity sha512-v2kDEe57lecTulaDIuNTPy3Ry4gLGJ6Z1O3vE1krgXZNrsQ+LFTGHVxVjcXPs17LhbZVGedAJv8XZ1tvj5FvSg==
+
+"my-stream@npm:event-stream@3.3.6":
+  version "3.3.6"
+`
+
+const yarnBerryLock = `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 6
+  cacheKey: 8
+
+"@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.10.4":
+  version: 7.12.13
+  resolution: "@babel/code-frame@npm:7.12.13"
+  dependencies:
+    "@babel/highlight": ^7.12.13
+  checksum: 471532bb7cf4224d2ca09ab6e1e8b8a3c8b5f1c1
+  languageName: node
+  linkType: hard
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+  languageName: unknown
+  linkType: soft
+
+"my-stream@npm:event-stream@3.3.6":
+  version: 3.3.6
+  resolution: "event-stream@npm:3.3.6"
+  languageName: node
+  linkType: hard
+`
+
+func TestParseYarnLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []PackageRef
+	}{
+		{
+			name:    "classic v1",
+			content: yarnV1Lock,
+			expected: []PackageRef{
+				{
+					Name:       "@babel/code-frame",
+					Version:    "7.12.13",
+					Resolved:   "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.12.13.tgz#dcfc826b",
+					Integrity:  "sha512-HV1Cm0Q3ZrpCR93tkWOYiuYIgLxZXZFVG2VgK+MBWjUqZTundupbfx2aXarXuw5Ko5aMcjtJgbSs4vUGBS5v6g==",
+					Descriptor: "@babel/code-frame@^7.0.0, @babel/code-frame@^7.10.4",
+				},
+				{
+					Name:       "lodash",
+					Version:    "4.17.21",
+					Resolved:   "https://registry.yarnpkg.com/lodash/-/lodash-4.17.21.tgz#679591c5",
+					Integrity:  "sha512-v2kDEe57lecTulaDIuNTPy3Ry4gLGJ6Z1O3vE1krgXZNrsQ+LFTGHVxVjcXPs17LhbZVGedAJv8XZ1tvj5FvSg==",
+					Descriptor: "lodash@^4.17.20",
+				},
+				{Name: "event-stream", Version: "3.3.6", Descriptor: "my-stream@npm:event-stream@3.3.6"},
+			},
+		},
+		{
+			name:    "berry",
+			content: yarnBerryLock,
+			expected: []PackageRef{
+				{
+					Name:       "@babel/code-frame",
+					Version:    "7.12.13",
+					Resolved:   "@babel/code-frame@npm:7.12.13",
+					Descriptor: "@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.10.4",
+				},
+				{Name: "event-stream", Version: "3.3.6", Resolved: "event-stream@npm:3.3.6", Descriptor: "my-stream@npm:event-stream@3.3.6"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			lockPath := filepath.Join(dir, "yarn.lock")
+			if err := os.WriteFile(lockPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write lockfile: %v", err)
+			}
+
+			packages, err := parseYarnLock(lockPath)
+			if err != nil {
+				t.Fatalf("parseYarnLock failed: %v", err)
+			}
+
+			if len(packages) != len(tt.expected) {
+				t.Fatalf("Expected %d packages, got %d: %+v", len(tt.expected), len(packages), packages)
+			}
+
+			for i, want := range tt.expected {
+				want.Path = dir
+				want.Lockfile = lockPath
+				if !reflect.DeepEqual(packages[i], want) {
+					t.Errorf("Package %d: expected %+v, got %+v", i, want, packages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDescriptorName(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		expected   string
+	}{
+		{"lodash@^4.17.0", "lodash"},
+		{"@scope/pkg@~1.2.0", "@scope/pkg"},
+		{"lodash@npm:^4.17.0", "lodash"},
+		{"alias@npm:real@^1.0.0", "real"},
+		{"alias@npm:@scope/real@^1.0.0", "@scope/real"},
+		{"@scope/pkg@npm:1.0.0", "@scope/pkg"},
+		{"bare", "bare"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.descriptor, func(t *testing.T) {
+			if got := descriptorName(tt.descriptor); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}